@@ -7,16 +7,24 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"time"
 
 	tx "github.com/rileyafox/solana-sentinel/api/gen/txrelay/v1"
 	apihttp "github.com/rileyafox/solana-sentinel/internal/api"
 	"github.com/rileyafox/solana-sentinel/internal/gateway"
+	"github.com/rileyafox/solana-sentinel/internal/metrics"
 	"github.com/rileyafox/solana-sentinel/internal/observability"
+	"github.com/rileyafox/solana-sentinel/internal/reconcile"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+	"github.com/rileyafox/solana-sentinel/internal/sigwatch"
+	"github.com/rileyafox/solana-sentinel/internal/slots"
 	"github.com/rileyafox/solana-sentinel/internal/store"
 	"github.com/rileyafox/solana-sentinel/internal/stream"
+	"github.com/rileyafox/solana-sentinel/internal/subs"
 	"github.com/rileyafox/solana-sentinel/internal/worker"
 
+	"github.com/redis/go-redis/v9"
 	"google.golang.org/grpc"
 	health "google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
@@ -25,7 +33,7 @@ import (
 func main() {
 	grpcAddr := getenv("GRPC_ADDR", ":8081")
 	restAddr := getenv("REST_ADDR", ":8080")
-	_ = getenv("METRICS_ADDR", ":9102")
+	metricsAddr := getenv("METRICS_ADDR", ":9102")
 
 	redisURL := getenv("REDIS_URL", "redis://redis:6379/0")
 	dsn := getenv("DATABASE_URL", "postgres://postgres:postgres@db:5432/sentinel?sslmode=disable")
@@ -35,6 +43,8 @@ func main() {
 	defer stop()
 	shutdown := observability.Init(ctx)
 	defer shutdown()
+	metrics.StartServer(metricsAddr)
+	rpc.DefaultHTTPHooks = metrics.HTTPHooks()
 
 	// ---- DB store for HTTP handlers ----
 	st, err := store.New(ctx, dsn)
@@ -46,6 +56,15 @@ func main() {
 	}
 	apihttp.SetStore(st) // make store available to LatestEventsHandler
 
+	// ---- Redis-backed ingester watch list for the admin API ----
+	ropt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		log.Fatalf("redis url: %v", err)
+	}
+	rdb := redis.NewClient(ropt)
+	defer rdb.Close()
+	apihttp.SetSubscriptionStore(subs.NewStore(rdb))
+
 	// ---- Background worker: Redis -> Postgres ----
 	go func() {
 		if err := worker.RunRedisToPostgres(context.Background()); err != nil {
@@ -53,6 +72,46 @@ func main() {
 		}
 	}()
 
+	// ---- Background reconciler: confirmed -> finalized / rolled_back ----
+	// SOLANA_HTTP_URLS takes a weighted pool ("https://a|3,https://b|1|archive").
+	httpc := rpc.NewHTTPPool(rpc.ParseHTTPEndpoints(getenv("SOLANA_HTTP_URLS", getenv("SOLANA_HTTP_URL", "https://api.mainnet-beta.solana.com"))))
+	httpc.StartHealthChecks(ctx)
+	go func() {
+		rec := reconcile.New(httpc, st, rdb)
+		if err := rec.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("reconciler exited: %v", err)
+		}
+	}()
+
+	// ---- Slot tracker: chain head + ingestion lag for Health and /metrics ----
+	wsURL := getenv("SOLANA_WS_URLS", getenv("SOLANA_WS_URL", "wss://api.mainnet-beta.solana.com"))
+	wsc := rpc.NewWSClient(wsURL)
+	wsc.Hooks = metrics.WSHooks()
+	if n, err := strconv.Atoi(getenv("WS_BUFFER", "256")); err == nil && n > 0 {
+		wsc.BufferSize = n
+	}
+	overflow, err := rpc.ParseOverflowPolicy(getenv("WS_OVERFLOW", "drop-newest"))
+	if err != nil {
+		log.Fatalf("WS_OVERFLOW: %v", err)
+	}
+	wsc.Overflow = overflow
+	wsc.Start(ctx)
+	go func() {
+		for err := range wsc.Errors() {
+			log.Printf("ws client: %v", err)
+		}
+	}()
+	tracker := slots.New(wsc, httpc, st)
+	apihttp.SetSlotTracker(tracker)
+	go func() {
+		if err := tracker.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("slot tracker exited: %v", err)
+		}
+	}()
+
+	// ---- WatchSignature: signatureSubscribe + getSignatureStatuses ----
+	apihttp.SetSignatureWatcher(sigwatch.New(wsc, httpc))
+
 	// ---- gRPC server + health ----
 	grpcSrv := grpc.NewServer()
 
@@ -80,12 +139,10 @@ func main() {
 
 	root := http.NewServeMux()
 	root.HandleFunc("/v1/events/latest", apihttp.LatestEventsHandler) // custom REST endpoint
-	// optional: simple health for REST plane
-	root.HandleFunc("/v1/health", func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(`{"status":"ok","plane":"rest"}`))
-	})
+	apihttp.SetAdminToken(getenv("ADMIN_TOKEN", ""))
+	root.HandleFunc("/v1/admin/subscriptions", apihttp.SubscriptionsHandler)
+	// simple health for REST plane, with chain head / ingestion lag
+	root.HandleFunc("/v1/health", apihttp.HealthHandler)
 	// everything else → grpc-gateway (OpenAPI/REST ↔ gRPC)
 	root.Handle("/", gw)
 
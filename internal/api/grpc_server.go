@@ -2,16 +2,33 @@ package api
 
 import (
 	"context"
+	"errors"
 	"net"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 
 	tx "github.com/rileyafox/solana-sentinel/api/gen/txrelay/v1"
+	"github.com/rileyafox/solana-sentinel/internal/sigwatch"
+	"github.com/rileyafox/solana-sentinel/internal/slots"
 	"github.com/rileyafox/solana-sentinel/internal/stream"
 )
 
+// Inject the slot tracker from main at startup (nil = slots unknown).
+var slotTracker *slots.Tracker
+
+// SetSlotTracker wires the chain-head tracker reported by Health.
+func SetSlotTracker(t *slots.Tracker) { slotTracker = t }
+
+// Inject the signature watcher from main at startup.
+var sigWatcher *sigwatch.Watcher
+
+// SetSignatureWatcher wires the watcher behind WatchSignature.
+func SetSignatureWatcher(w *sigwatch.Watcher) { sigWatcher = w }
+
 type Server struct {
 	tx.UnimplementedSentinelServer
 	streamer *stream.Streamer
@@ -23,7 +40,16 @@ func NewServer(s *stream.Streamer, version string) *Server {
 }
 
 func (s *Server) Health(ctx context.Context, _ *tx.HealthRequest) (*tx.HealthResponse, error) {
-	return &tx.HealthResponse{Status: "ok", Version: s.version}, nil
+	sl := slotTracker.Snapshot()
+	return &tx.HealthResponse{
+		Status:         "ok",
+		Version:        s.version,
+		ProcessedSlot:  sl.Processed,
+		ConfirmedSlot:  sl.Confirmed,
+		FinalizedSlot:  sl.Finalized,
+		PersistedSlot:  sl.Persisted,
+		IngestLagSlots: sl.IngestLag,
+	}, nil
 }
 
 // Stream delegates to the streamer, which already handles
@@ -33,6 +59,27 @@ func (s *Server) Stream(req *tx.StreamRequest, srv tx.Sentinel_StreamServer) err
 	return s.streamer.StreamToClient(srv.Context(), req, srv)
 }
 
+// WatchSignature streams status transitions for one signature until it
+// reaches req.Until, expires, or the client goes away.
+func (s *Server) WatchSignature(req *tx.WatchSignatureRequest, srv tx.Sentinel_WatchSignatureServer) error {
+	if sigWatcher == nil {
+		return status.Error(codes.Unavailable, "signature watcher unavailable")
+	}
+	err := sigWatcher.Watch(srv.Context(), req.Signature, req.Until, req.LastValidBlockHeight, func(u sigwatch.Update) error {
+		return srv.Send(&tx.SignatureStatusUpdate{
+			Signature: u.Signature,
+			Status:    u.Status,
+			Slot:      u.Slot,
+			Err:       u.Err,
+			TsMs:      u.At.UnixMilli(),
+		})
+	})
+	if errors.Is(err, sigwatch.ErrInvalid) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
+
 func RunGRPC(addr string, s *Server) error {
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -16,11 +16,12 @@ var dbStore *store.Store
 func SetStore(s *store.Store) { dbStore = s }
 
 type EventRow struct {
-	Signature string  `json:"signature"`
-	Slot      int64   `json:"slot"`
-	Err       *string `json:"err"`        // raw JSON as string; null shows as null
-	Logs      string  `json:"logs"`
-	CreatedAt string  `json:"created_at"`
+	Signature  string  `json:"signature"`
+	Slot       int64   `json:"slot"`
+	Err        *string `json:"err"`        // raw JSON as string; null shows as null
+	Logs       string  `json:"logs"`
+	Commitment string  `json:"commitment"` // processed|confirmed|finalized|rolled_back
+	CreatedAt  string  `json:"created_at"`
 }
 
 func LatestEventsHandler(w http.ResponseWriter, r *http.Request) {
@@ -56,7 +57,7 @@ func LatestEventsHandler(w http.ResponseWriter, r *http.Request) {
 	sql := `
 SELECT signature, slot,
        CASE WHEN err::text = 'null' THEN NULL ELSE err::text END AS err,
-       logs, to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SS.MS"Z"') AS created_at
+       logs, commitment, to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SS.MS"Z"') AS created_at
 FROM tx_events
 WHERE ` + where + `
 ORDER BY slot DESC
@@ -72,7 +73,7 @@ LIMIT $` + itoa(len(args))
 	list := make([]EventRow, 0, n)
 	for rows.Next() {
 		var e EventRow
-		if err := rows.Scan(&e.Signature, &e.Slot, &e.Err, &e.Logs, &e.CreatedAt); err != nil {
+		if err := rows.Scan(&e.Signature, &e.Slot, &e.Err, &e.Logs, &e.Commitment, &e.CreatedAt); err != nil {
 			http.Error(w, "scan failed", http.StatusInternalServerError)
 			return
 		}
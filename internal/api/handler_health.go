@@ -0,0 +1,18 @@
+package api
+
+import "net/http"
+
+// HealthHandler is the REST-plane health check. It mirrors the Health RPC's
+// slot fields so lag is visible without going through the gateway.
+func HealthHandler(w http.ResponseWriter, _ *http.Request) {
+	sl := slotTracker.Snapshot()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":           "ok",
+		"plane":            "rest",
+		"processed_slot":   sl.Processed,
+		"confirmed_slot":   sl.Confirmed,
+		"finalized_slot":   sl.Finalized,
+		"persisted_slot":   sl.Persisted,
+		"ingest_lag_slots": sl.IngestLag,
+	})
+}
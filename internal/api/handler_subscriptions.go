@@ -0,0 +1,111 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rileyafox/solana-sentinel/internal/subs"
+)
+
+// Inject the subscription store from main at startup.
+var subStore *subs.Store
+
+// SetSubscriptionStore wires the shared watch-list store.
+func SetSubscriptionStore(s *subs.Store) { subStore = s }
+
+// adminToken guards the watch-list writes; empty disables them.
+var adminToken string
+
+// SetAdminToken sets the bearer token POST and DELETE require (ADMIN_TOKEN).
+func SetAdminToken(t string) { adminToken = t }
+
+// authorizeAdmin reports whether r carries the admin token, answering 403
+// (writes disabled) or 401 when it does not.
+func authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if adminToken == "" {
+		http.Error(w, "admin writes disabled (set ADMIN_TOKEN)", http.StatusForbidden)
+		return false
+	}
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(adminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// SubscriptionsHandler manages the ingester watch list.
+//
+//	GET    /v1/admin/subscriptions          list
+//	POST   /v1/admin/subscriptions          add or replace {"kind","address","commitment"}
+//	DELETE /v1/admin/subscriptions?id=...   remove
+//
+// POST and DELETE need "Authorization: Bearer $ADMIN_TOKEN".
+func SubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	if subStore == nil {
+		http.Error(w, "subscription store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if (r.Method == http.MethodPost || r.Method == http.MethodDelete) && !authorizeAdmin(w, r) {
+		return
+	}
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := subStore.List(ctx)
+		if err != nil {
+			http.Error(w, "list failed", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"count": len(list), "items": list})
+
+	case http.MethodPost:
+		var in subs.Subscription
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		out, err := subStore.Put(ctx, in)
+		if errors.Is(err, subs.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "save failed", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, out)
+
+	case http.MethodDelete:
+		id := strings.TrimSpace(r.URL.Query().Get("id"))
+		if id == "" {
+			http.Error(w, "id required", http.StatusBadRequest)
+			return
+		}
+		ok, err := subStore.Delete(ctx, id)
+		if err != nil {
+			http.Error(w, "delete failed", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -22,32 +22,111 @@ func New(http *rpc.HTTPClient, st *store.Store) *Backfill {
 }
 
 // ScanAccount fetches recent signatures for an address (account or program) and persists tx + events.
+// Transactions are fetched with batched getTransaction calls, one batch at a time.
 func (b *Backfill) ScanAccount(ctx context.Context, pubkey string, limit int) error {
 	sigs, err := b.HTTP.GetSignaturesForAddress(ctx, pubkey, limit, "")
 	if err != nil {
 		return err
 	}
 	log.Printf("backfill: %d signatures for %s", len(sigs), pubkey)
-	for i, s := range sigs {
+
+	batch := max(b.HTTP.MaxBatch, 1)
+	for start := 0; start < len(sigs); start += batch {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
-		txres, err := b.HTTP.GetTransaction(ctx, s.Signature)
-		if err != nil {
-			log.Printf("getTransaction[%d] %s: %v", i, s.Signature, err)
-			continue
+		chunk := sigs[start:min(start+batch, len(sigs))]
+		oldest := chunk[0].Slot
+		for _, s := range chunk {
+			oldest = min(oldest, s.Slot)
 		}
-		txRow, events := parse.FromGetTransaction(s.Signature, txres.Transaction, txres.Meta, txres.Slot, txres.BlockTime)
+		// With an HTTP pool, old chunks go to an archive endpoint.
+		txs, at, errs := b.fetch(rpc.WithSlot(ctx, oldest), chunk)
+		for i, s := range chunk {
+			if errs[i] != nil {
+				log.Printf("getTransaction[%d] %s: %v", start+i, s.Signature, errs[i])
+				continue
+			}
+			txres := txs[i]
+			if txres == nil {
+				log.Printf("getTransaction[%d] %s: not found", start+i, s.Signature)
+				continue
+			}
+			txRow, events := parse.FromGetTransaction(s.Signature, txres)
+			// The commitment it was fetched (or served from the RPC cache) at;
+			// the reconciler only re-checks rows that are not finalized yet.
+			txRow.Commitment = at[i]
 
-		if err := b.Store.InsertTransaction(ctx, txRow); err != nil {
-			return fmt.Errorf("insert tx %s: %w", txRow.Signature, err)
-		}
-		if err := b.Store.ReplaceEventsForSignature(ctx, txRow.Signature, events); err != nil {
-			return fmt.Errorf("replace events %s: %w", txRow.Signature, err)
+			if err := b.Store.InsertTransaction(ctx, txRow); err != nil {
+				return fmt.Errorf("insert tx %s: %w", txRow.Signature, err)
+			}
+			if err := b.Store.ReplaceEventsForSignature(ctx, txRow.Signature, events); err != nil {
+				return fmt.Errorf("replace events %s: %w", txRow.Signature, err)
+			}
 		}
-		time.Sleep(120 * time.Millisecond)
 	}
 	return nil
 }
+
+// fetchRounds bounds how often fetch asks again for transactions that failed
+// with a retryable error.
+const fetchRounds = 3
+
+// fetch gets the transactions of sigs, re-requesting the ones whose errors
+// are retryable (node behind, rate limited, transport) after a short pause.
+// Final errors such as pruned history are returned as they are. at[i] is
+// the commitment txs[i] was fetched at.
+func (b *Backfill) fetch(ctx context.Context, sigs []rpc.SignatureInfo) (txs []*rpc.GetTransactionResult, at []string, errs []error) {
+	txs = make([]*rpc.GetTransactionResult, len(sigs))
+	at = make([]string, len(sigs))
+	errs = make([]error, len(sigs))
+	pending := make([]int, len(sigs))
+	for i := range pending {
+		pending[i] = i
+	}
+	for round := 0; round < fetchRounds && len(pending) > 0; round++ {
+		if round > 0 {
+			select {
+			case <-ctx.Done():
+				return txs, at, errs
+			case <-time.After(time.Duration(round) * time.Second):
+			}
+			log.Printf("backfill: retrying %d transactions", len(pending))
+		}
+		b.get(ctx, sigs, pending, txs, at, errs)
+		var retry []int
+		for _, i := range pending {
+			if rpc.IsRetryable(errs[i]) {
+				retry = append(retry, i)
+			}
+		}
+		pending = retry
+	}
+	return txs, at, errs
+}
+
+// get fetches sigs[i] for each i in idx into txs[i] and errs[i]. Signatures
+// already finalized are asked for at finalized commitment: their results
+// never change, so an RPC cache (RPC_CACHE) can keep them for the next run.
+func (b *Backfill) get(ctx context.Context, sigs []rpc.SignatureInfo, idx []int, txs []*rpc.GetTransactionResult, at []string, errs []error) {
+	groups := map[string][]int{}
+	for _, i := range idx {
+		commitment := store.CommitmentConfirmed
+		if sigs[i].ConfirmationStatus == store.CommitmentFinalized {
+			commitment = store.CommitmentFinalized
+		}
+		groups[commitment] = append(groups[commitment], i)
+	}
+	for commitment, group := range groups {
+		names := make([]string, len(group))
+		for j, i := range group {
+			names[j] = sigs[i].Signature
+		}
+		gtxs, gerrs := b.HTTP.GetTransactionsAt(ctx, names, commitment)
+		for j, i := range group {
+			txs[i], at[i], errs[i] = gtxs[j], commitment, gerrs[j]
+		}
+	}
+}
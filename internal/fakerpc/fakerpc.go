@@ -0,0 +1,502 @@
+// Package fakerpc is a local stand-in for a Solana RPC node (HTTP JSON-RPC
+// and WebSocket pubsub) that serves canned fixtures, for running Sentinel
+// offline and for exercising its failure handling.
+package fakerpc
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+)
+
+//go:embed fixtures/sample.json
+var sampleFixtures []byte
+
+// LogFixture is one transaction emitted over logsSubscribe.
+type LogFixture struct {
+	Signature string   `json:"signature"`
+	Err       any      `json:"err"`
+	Logs      []string `json:"logs"`
+	Mentions  []string `json:"mentions"` // addresses a mentions filter matches
+}
+
+// Fixtures is the canned chain state. Signatures are per address, newest
+// first; transactions and accounts are raw getTransaction / getAccountInfo
+// values.
+type Fixtures struct {
+	Slot         uint64                         `json:"slot"`
+	Signatures   map[string][]rpc.SignatureInfo `json:"signatures"`
+	Transactions map[string]json.RawMessage     `json:"transactions"`
+	Accounts     map[string]json.RawMessage     `json:"accounts"`
+	Logs         []LogFixture                   `json:"logs"`
+}
+
+// LoadFixtures reads fixtures from path, or the built-in sample when path is "".
+func LoadFixtures(path string) (*Fixtures, error) {
+	raw := sampleFixtures
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		raw = b
+	}
+	var f Fixtures
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+	return &f, nil
+}
+
+// Server implements getSlot, getSignaturesForAddress, getTransaction,
+// getAccountInfo over HTTP (single requests and batches) and logsSubscribe / slotSubscribe over WebSocket
+// on the same address.
+type Server struct {
+	Fixtures *Fixtures
+
+	// Fault injection; zero values disable each one.
+	Latency        time.Duration // added before every HTTP reply and notification
+	ErrorRate      float64       // fraction of HTTP requests answered with 500
+	RateLimitEvery int           // every Nth HTTP request is answered with 429
+	DropAfter      time.Duration // WebSocket connections are closed after this long
+	MaxBatch       int           // larger JSON-RPC batches are refused with an error object
+
+	SlotInterval time.Duration // how fast the fake chain advances
+	LogInterval  time.Duration // gap between log notifications
+	LoopLogs     bool          // replay the log fixtures forever (at new slots)
+
+	slot     atomic.Uint64
+	requests atomic.Int64
+	upgrader websocket.Upgrader
+}
+
+func New(f *Fixtures) *Server {
+	s := &Server{
+		Fixtures:     f,
+		SlotInterval: 400 * time.Millisecond,
+		LogInterval:  2 * time.Second,
+		upgrader:     websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }},
+	}
+	s.slot.Store(f.Slot)
+	return s
+}
+
+// Run advances the slot and serves on addr until the listener fails.
+func (s *Server) Run(addr string) error {
+	go func() {
+		for range time.Tick(s.SlotInterval) {
+			s.slot.Add(1)
+		}
+	}()
+	log.Printf("fakerpc: listening on %s (http + ws)", addr)
+	return http.ListenAndServe(addr, s)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWS(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST JSON-RPC", http.StatusMethodNotAllowed)
+		return
+	}
+
+	n := s.requests.Add(1)
+	time.Sleep(s.Latency)
+	if s.RateLimitEvery > 0 && n%int64(s.RateLimitEvery) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":429,"message":"Too many requests for a specific RPC call"}}`))
+		return
+	}
+	if s.ErrorRate > 0 && rand.Float64() < s.ErrorRate {
+		http.Error(w, "injected failure", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		s.serveBatch(w, body)
+		return
+	}
+	var req rpcReq
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeRPC(w, nil, nil, &rpcErr{-32700, "parse error"})
+		return
+	}
+	result, rerr := s.call(req.Method, req.Params)
+	writeRPC(w, req.ID, result, rerr)
+}
+
+type rpcReq struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// serveBatch answers a JSON-RPC batch, in reverse order to keep clients
+// honest about matching replies by id.
+func (s *Server) serveBatch(w http.ResponseWriter, body []byte) {
+	var reqs []rpcReq
+	if err := json.Unmarshal(body, &reqs); err != nil {
+		writeRPC(w, nil, nil, &rpcErr{-32700, "parse error"})
+		return
+	}
+	if s.MaxBatch > 0 && len(reqs) > s.MaxBatch {
+		writeRPC(w, nil, nil, &rpcErr{-32600, fmt.Sprintf("batch of %d exceeds limit %d", len(reqs), s.MaxBatch)})
+		return
+	}
+	out := make([]map[string]any, 0, len(reqs))
+	for i := len(reqs) - 1; i >= 0; i-- {
+		result, rerr := s.call(reqs[i].Method, reqs[i].Params)
+		resp := map[string]any{"jsonrpc": "2.0", "id": reqs[i].ID}
+		if rerr != nil {
+			resp["error"] = rerr
+		} else {
+			resp["result"] = result
+		}
+		out = append(out, resp)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+type rpcErr struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeRPC(w http.ResponseWriter, id json.RawMessage, result any, e *rpcErr) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := map[string]any{"jsonrpc": "2.0", "id": id}
+	if e != nil {
+		resp["error"] = e
+	} else {
+		resp["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) call(method string, params []json.RawMessage) (any, *rpcErr) {
+	arg := func(i int, v any) {
+		if i < len(params) {
+			_ = json.Unmarshal(params[i], v)
+		}
+	}
+	switch method {
+	case "getSlot", "getBlockHeight":
+		return s.slot.Load(), nil
+
+	case "getSignaturesForAddress":
+		var addr string
+		var opts rpc.SignaturesOpts
+		arg(0, &addr)
+		arg(1, &opts)
+		return s.signatures(addr, opts), nil
+
+	case "getTransaction":
+		var sig string
+		arg(0, &sig)
+		if tx, ok := s.Fixtures.Transactions[sig]; ok {
+			return tx, nil
+		}
+		return nil, nil
+
+	case "getAccountInfo":
+		var pubkey string
+		arg(0, &pubkey)
+		var value any // null for unknown accounts
+		if a, ok := s.Fixtures.Accounts[pubkey]; ok {
+			value = a
+		}
+		return map[string]any{"context": map[string]any{"slot": s.slot.Load()}, "value": value}, nil
+
+	case "getMultipleAccounts":
+		var pubkeys []string
+		arg(0, &pubkeys)
+		out := make([]any, len(pubkeys))
+		for i, pk := range pubkeys {
+			if a, ok := s.Fixtures.Accounts[pk]; ok {
+				out[i] = a
+			}
+		}
+		return map[string]any{"context": map[string]any{"slot": s.slot.Load()}, "value": out}, nil
+
+	case "getBalance":
+		var pubkey string
+		arg(0, &pubkey)
+		var a struct {
+			Lamports uint64 `json:"lamports"`
+		}
+		_ = json.Unmarshal(s.Fixtures.Accounts[pubkey], &a)
+		return map[string]any{"context": map[string]any{"slot": s.slot.Load()}, "value": a.Lamports}, nil
+
+	case "getEpochInfo":
+		slot := s.slot.Load()
+		return map[string]any{"absoluteSlot": slot, "blockHeight": slot, "epoch": slot / 432000,
+			"slotIndex": slot % 432000, "slotsInEpoch": 432000}, nil
+
+	case "getLatestBlockhash":
+		slot := s.slot.Load()
+		return map[string]any{"context": map[string]any{"slot": slot},
+			"value": map[string]any{"blockhash": "EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N", "lastValidBlockHeight": slot + 150}}, nil
+
+	case "getRecentPrioritizationFees":
+		slot := s.slot.Load()
+		out := make([]map[string]any, 0, 150)
+		for i := uint64(0); i < 150 && i <= slot; i++ {
+			out = append(out, map[string]any{"slot": slot - i, "prioritizationFee": (slot - i) % 7 * 1000})
+		}
+		return out, nil
+
+	case "getSignatureStatuses":
+		var sigs []string
+		arg(0, &sigs)
+		out := make([]any, len(sigs))
+		for i, sig := range sigs {
+			if si := s.findSignature(sig); si != nil {
+				out[i] = map[string]any{"slot": si.Slot, "confirmations": nil, "err": si.Err, "confirmationStatus": "finalized"}
+			}
+		}
+		return map[string]any{"context": map[string]any{"slot": s.slot.Load()}, "value": out}, nil
+
+	default:
+		return nil, &rpcErr{-32601, "Method not found"}
+	}
+}
+
+// signatures pages the fixture list like the real node: newest first,
+// below Before, stopping at Until.
+func (s *Server) signatures(addr string, opts rpc.SignaturesOpts) []rpc.SignatureInfo {
+	limit := opts.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+	out := []rpc.SignatureInfo{}
+	started := opts.Before == ""
+	for _, si := range s.Fixtures.Signatures[addr] {
+		if !started {
+			started = si.Signature == opts.Before
+			continue
+		}
+		if si.Signature == opts.Until || len(out) == limit {
+			break
+		}
+		out = append(out, si)
+	}
+	return out
+}
+
+func (s *Server) findSignature(sig string) *rpc.SignatureInfo {
+	for _, list := range s.Fixtures.Signatures {
+		for i := range list {
+			if list[i].Signature == sig {
+				return &list[i]
+			}
+		}
+	}
+	return nil
+}
+
+// wsConn serialises writes to one WebSocket client.
+type wsConn struct {
+	mu     sync.Mutex
+	c      *websocket.Conn
+	nextID int
+	subs   map[int]chan struct{} // closed on unsubscribe
+}
+
+func (c *wsConn) send(v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.c.WriteJSON(v)
+}
+
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	wc := &wsConn{c: conn, nextID: 1, subs: map[int]chan struct{}{}}
+	done := make(chan struct{})
+	defer close(done)
+
+	if s.DropAfter > 0 {
+		t := time.AfterFunc(s.DropAfter, func() {
+			log.Printf("fakerpc: dropping ws connection (DropAfter)")
+			_ = conn.Close()
+		})
+		defer t.Stop()
+	}
+
+	for {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		switch req.Method {
+		case "logsSubscribe", "slotSubscribe":
+			stop := make(chan struct{})
+			wc.mu.Lock()
+			id := wc.nextID
+			wc.nextID++
+			wc.subs[id] = stop
+			wc.mu.Unlock()
+			_ = wc.send(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": id})
+			if req.Method == "slotSubscribe" {
+				go s.emitSlots(wc, id, done, stop)
+			} else {
+				var filter json.RawMessage
+				if len(req.Params) > 0 {
+					filter = req.Params[0]
+				}
+				go s.emitLogs(wc, id, mentionsFilter(filter), done, stop)
+			}
+		case "logsUnsubscribe", "slotUnsubscribe", "accountUnsubscribe", "programUnsubscribe":
+			var id int
+			if len(req.Params) > 0 {
+				_ = json.Unmarshal(req.Params[0], &id)
+			}
+			wc.mu.Lock()
+			stop, ok := wc.subs[id]
+			delete(wc.subs, id)
+			wc.mu.Unlock()
+			if !ok {
+				_ = wc.send(map[string]any{"jsonrpc": "2.0", "id": req.ID, "error": rpcErr{-32602, "Invalid subscription id."}})
+				continue
+			}
+			close(stop)
+			_ = wc.send(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": true})
+		default:
+			_ = wc.send(map[string]any{"jsonrpc": "2.0", "id": req.ID, "error": rpcErr{-32601, "Method not found"}})
+		}
+	}
+}
+
+// mentionsFilter returns the address of a {"mentions":[addr]} filter, or ""
+// for "all" / "allWithVotes".
+func mentionsFilter(raw json.RawMessage) string {
+	var f struct {
+		Mentions []string `json:"mentions"`
+	}
+	if json.Unmarshal(raw, &f) == nil && len(f.Mentions) > 0 {
+		return f.Mentions[0]
+	}
+	return ""
+}
+
+// stopped reports whether the connection closed or the subscription was
+// cancelled.
+func stopped(done, stop <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Server) emitLogs(wc *wsConn, subID int, mention string, done, stop <-chan struct{}) {
+	t := time.NewTicker(s.LogInterval)
+	defer t.Stop()
+	for {
+		sent := 0
+		for _, lf := range s.Fixtures.Logs {
+			if mention != "" && !contains(lf.Mentions, mention) {
+				continue
+			}
+			sent++
+			select {
+			case <-done:
+				return
+			case <-stop:
+				return
+			case <-t.C:
+			}
+			time.Sleep(s.Latency)
+			if stopped(done, stop) {
+				return
+			}
+			err := wc.send(map[string]any{
+				"jsonrpc": "2.0",
+				"method":  "logsNotification",
+				"params": map[string]any{
+					"subscription": subID,
+					"result": map[string]any{
+						"context": map[string]any{"slot": s.slot.Load()},
+						"value":   map[string]any{"signature": lf.Signature, "err": lf.Err, "logs": lf.Logs},
+					},
+				},
+			})
+			if err != nil {
+				return
+			}
+		}
+		if !s.LoopLogs || sent == 0 {
+			return
+		}
+	}
+}
+
+func (s *Server) emitSlots(wc *wsConn, subID int, done, stop <-chan struct{}) {
+	t := time.NewTicker(s.SlotInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		slot := s.slot.Load()
+		err := wc.send(map[string]any{
+			"jsonrpc": "2.0",
+			"method":  "slotNotification",
+			"params": map[string]any{
+				"subscription": subID,
+				"result":       map[string]any{"slot": slot, "parent": slot - 1, "root": slot - 32},
+			},
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,9 +2,11 @@ package metrics
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
 )
 
 var (
@@ -40,8 +42,122 @@ var (
 			Help: "redis reconnect attempts",
 		},
 	)
+	ChainSlot = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_chain_slot",
+			Help: "latest chain slot seen, by commitment",
+		},
+		[]string{"commitment"},
+	)
+	PersistedSlot = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sentinel_persisted_slot",
+			Help: "newest slot persisted in tx_events",
+		},
+	)
+	IngestLag = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sentinel_ingest_lag_slots",
+			Help: "processed head slot minus newest persisted slot",
+		},
+	)
+	CommitmentLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_commitment_lag_slots",
+			Help: "processed head slot minus the confirmed/finalized slot",
+		},
+		[]string{"commitment"},
+	)
+	WSDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_ws_client_dropped_total",
+			Help: "notifications discarded by the WS client overflow policy, by method",
+		},
+		[]string{"method"},
+	)
+	WSSubscribeErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_ws_client_subscribe_errors_total",
+			Help: "subscribe requests rejected by the node, by method",
+		},
+		[]string{"method"},
+	)
+	WSReconnects = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "sentinel_ws_client_reconnects_total",
+			Help: "WS client connections that ended and were redialed",
+		},
+	)
+	RPCLimiterWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "sentinel_rpc_limiter_wait_seconds",
+			Help:    "time HTTP RPC requests waited in the rate limiter, by method",
+			Buckets: []float64{0, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"method"},
+	)
+	RPCThrottled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_rpc_throttled_total",
+			Help: "HTTP RPC requests answered with 429, by method",
+		},
+		[]string{"method"},
+	)
+	RPCFailovers = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_rpc_failovers_total",
+			Help: "HTTP RPC calls moved to another pool endpoint after failing, by method",
+		},
+		[]string{"method"},
+	)
+	RPCEndpointHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_rpc_endpoint_healthy",
+			Help: "1 while an HTTP pool endpoint is in rotation, 0 while ejected",
+		},
+		[]string{"endpoint"},
+	)
+	RPCCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_rpc_cache_requests_total",
+			Help: "cacheable HTTP RPC calls looked up in the response cache, by method and result (hit|miss)",
+		},
+		[]string{"method", "result"},
+	)
 )
 
+// WSHooks feeds rpc.WSClient events into the WS client metrics.
+func WSHooks() rpc.WSHooks {
+	return rpc.WSHooks{
+		OnDrop:           func(method string) { WSDropped.WithLabelValues(method).Inc() },
+		OnSubscribeError: func(method string, _ error) { WSSubscribeErrors.WithLabelValues(method).Inc() },
+		OnReconnect:      func(string, error) { WSReconnects.Inc() },
+	}
+}
+
+// HTTPHooks feeds rpc.HTTPClient limiter, 429, pool and cache events into the RPC metrics.
+func HTTPHooks() rpc.HTTPHooks {
+	return rpc.HTTPHooks{
+		OnWait:      func(_, method string, d time.Duration) { RPCLimiterWait.WithLabelValues(method).Observe(d.Seconds()) },
+		OnThrottled: func(_, method string) { RPCThrottled.WithLabelValues(method).Inc() },
+		OnFailover:  func(_, method string) { RPCFailovers.WithLabelValues(method).Inc() },
+		OnCache: func(method string, hit bool) {
+			result := "miss"
+			if hit {
+				result = "hit"
+			}
+			RPCCacheRequests.WithLabelValues(method, result).Inc()
+		},
+		OnEndpointHealth: func(endpoint string, ok bool) {
+			v := 0.0
+			if ok {
+				v = 1
+			}
+			RPCEndpointHealthy.WithLabelValues(endpoint).Set(v)
+		},
+	}
+}
+
 // init pre-creates common label series at 0 so they show up immediately in Prometheus,
 // even before the first event flows through.
 func init() {
@@ -60,6 +176,18 @@ func StartServer(addr string) {
 		StreamErrors,
 		RedisErrors,
 		RedisReconnects,
+		ChainSlot,
+		PersistedSlot,
+		IngestLag,
+		CommitmentLag,
+		WSDropped,
+		WSSubscribeErrors,
+		WSReconnects,
+		RPCLimiterWait,
+		RPCThrottled,
+		RPCFailovers,
+		RPCEndpointHealthy,
+		RPCCacheRequests,
 	)
 
 	mux := http.NewServeMux()
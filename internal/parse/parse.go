@@ -2,43 +2,39 @@
 package parse
 
 import (
-	"bytes"
+	"encoding/binary"
 	"encoding/json"
-	"fmt"
-	// "strconv"
+	"strconv"
 	"time"
 
 	"github.com/rileyafox/solana-sentinel/internal/store"
+	"github.com/rileyafox/solana-sentinel/pkg/types"
 )
 
-// FromGetTransaction normalizes a getTransaction JSON into TxRow + EventRows (basic transfer + program_log).
-func FromGetTransaction(signature string, tx map[string]any, meta map[string]any, slot uint64, blockTime *int64) (store.TxRow, []store.EventRow) {
-	var fee int64 = 0
-	if v, ok := meta["fee"].(float64); ok {
-		fee = int64(v)
+// SystemProgram is the System program's id.
+const SystemProgram = "11111111111111111111111111111111"
+
+// FromGetTransaction normalizes a getTransaction result into TxRow + EventRows (basic transfer + program_log).
+func FromGetTransaction(signature string, res *types.TransactionWithMeta) (store.TxRow, []store.EventRow) {
+	meta := res.Meta
+	if meta == nil {
+		meta = &types.TxMeta{}
 	}
 	var bt *time.Time
-	if blockTime != nil {
-		t := time.Unix(*blockTime, 0).UTC()
+	if res.BlockTime != nil {
+		t := time.Unix(*res.BlockTime, 0).UTC()
 		bt = &t
 	}
 
-	// Marshal raw JSON bodies for audit
-	raw := mustJSON(map[string]any{"slot": slot, "blockTime": blockTime, "meta": meta, "transaction": tx})
-
-	var errJSON []byte
-	if e, ok := meta["err"]; ok && e != nil {
-		errJSON = mustJSON(e)
-	} else {
-		errJSON = []byte("null")
-	}
+	// Marshal raw JSON bodies for audit (the node's own JSON, see types.Transaction.MarshalJSON)
+	raw := mustJSON(map[string]any{"slot": res.Slot, "blockTime": res.BlockTime, "meta": res.Meta, "transaction": res.Transaction})
 
 	txRow := store.TxRow{
 		Signature: signature,
-		Slot:      int64(slot),
+		Slot:      int64(res.Slot),
 		BlockTime: bt,
-		Fee:       fee,
-		ErrJSON:   errJSON,
+		Fee:       int64(meta.Fee),
+		ErrJSON:   mustJSON(meta.Err),
 		RawJSON:   raw,
 	}
 
@@ -49,44 +45,36 @@ func FromGetTransaction(signature string, tx map[string]any, meta map[string]any
 		occur = *bt
 	}
 
-	// 1) Try to extract system "transfer" from jsonParsed
-	// transaction.message.instructions[*].parsed.type == "transfer"
-	if msg, ok := tx["message"].(map[string]any); ok {
-		if insts, ok := msg["instructions"].([]any); ok {
-			for _, it := range insts {
-				im, _ := it.(map[string]any)
-				parsed, _ := im["parsed"].(map[string]any)
-				if parsed == nil { continue }
-				if t, _ := parsed["type"].(string); t == "transfer" {
-					info, _ := parsed["info"].(map[string]any)
-					src, _ := toString(info["source"])
-					dst, _ := toString(info["destination"])
-					lamportsStr := toNumericString(info["lamports"])
-					raw := mustJSON(map[string]any{"source": src, "destination": dst, "lamports": info["lamports"]})
-					// store amount as lamports numeric string
-					evs = append(evs, store.EventRow{
-						Kind:       "transfer",
-						Signature:  signature,
-						Slot:       int64(slot),
-						Account:    ptrOrNil(dst),
-						Program:    ptrOrNil("11111111111111111111111111111111"),
-						Amount:     ptrOrNil(lamportsStr),
-						Mint:       nil,
-						RawJSON:    raw,
-						OccurredAt: occur,
-					})
-				}
-			}
+	// 1) System "transfer" instructions, parsed by the node (jsonParsed) or here
+	for _, in := range res.Transaction.Message.Instructions {
+		if in.ProgramID != SystemProgram {
+			continue
 		}
+		t, ok := systemTransfer(&in)
+		if !ok {
+			continue
+		}
+		lamports := strconv.FormatUint(t.Lamports, 10)
+		evs = append(evs, store.EventRow{
+			Kind:       "transfer",
+			Signature:  signature,
+			Slot:       int64(res.Slot),
+			Account:    ptrOrNil(t.Destination),
+			Program:    ptrOrNil(SystemProgram),
+			Amount:     ptrOrNil(lamports), // lamports as a numeric string
+			Mint:       nil,
+			RawJSON:    mustJSON(t),
+			OccurredAt: occur,
+		})
 	}
 
 	// 2) Also emit a generic program_log if logs exist
-	if logs, ok := meta["logMessages"].([]any); ok && len(logs) > 0 {
+	if logs := meta.LogMessages; len(logs) > 0 {
 		raw := mustJSON(map[string]any{"logs": logs})
 		evs = append(evs, store.EventRow{
 			Kind:       "program_log",
 			Signature:  signature,
-			Slot:       int64(slot),
+			Slot:       int64(res.Slot),
 			Account:    nil,
 			Program:    nil,
 			Amount:     nil,
@@ -99,36 +87,35 @@ func FromGetTransaction(signature string, tx map[string]any, meta map[string]any
 	return txRow, evs
 }
 
-func mustJSON(v any) []byte {
-	b, _ := json.Marshal(v)
-	if len(b) == 0 {
-		return []byte("null")
-	}
-	return b
+type transfer struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Lamports    uint64 `json:"lamports"`
 }
 
-func toString(v any) (string, bool) {
-	switch t := v.(type) {
-	case string:
-		return t, true
-	case nil:
-		return "", false
-	default:
-		b, _ := json.Marshal(t)
-		return string(bytes.Trim(b, `"`)), true
+// systemTransfer reads a System program transfer from the node's parsed form
+// or, for json/base64 encodings, from the instruction data: a little-endian
+// u32 instruction index (2) followed by a u64 lamports amount.
+func systemTransfer(in *types.Instruction) (transfer, bool) {
+	var t transfer
+	if typ, info, ok := in.ParsedInfo(); ok {
+		return t, typ == "transfer" && json.Unmarshal(info, &t) == nil
+	}
+	data, err := types.Base58Decode(in.Data)
+	if err != nil || len(data) != 12 || binary.LittleEndian.Uint32(data) != 2 || len(in.Accounts) < 2 {
+		return t, false
 	}
+	t.Source, t.Destination = in.Accounts[0], in.Accounts[1]
+	t.Lamports = binary.LittleEndian.Uint64(data[4:])
+	return t, true
 }
 
-func toNumericString(v any) string {
-	switch t := v.(type) {
-	case float64:
-		return fmt.Sprintf("%.0f", t)
-	case string:
-		return t
-	default:
-		b, _ := json.Marshal(t)
-		return string(bytes.Trim(b, `"`))
+func mustJSON(v any) []byte {
+	b, _ := json.Marshal(v)
+	if len(b) == 0 {
+		return []byte("null")
 	}
+	return b
 }
 
 func ptrOrNil(s string) *string {
@@ -0,0 +1,136 @@
+package reconcile
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+	"github.com/rileyafox/solana-sentinel/internal/store"
+)
+
+// StatusStream carries commitment changes for consumers that need to react to
+// finalization or reorgs.
+const StatusStream = "sol:status"
+
+// Reconciler upgrades tx_events rows from processed/confirmed to finalized
+// using getSignatureStatuses, and marks rows the ledger no longer knows as
+// rolled back (their slot was on a dropped fork).
+type Reconciler struct {
+	HTTP  *rpc.HTTPClient
+	Store *store.Store
+	Redis *redis.Client // optional; status changes are only persisted when nil
+
+	Interval      time.Duration
+	Batch         int           // signatures per getSignatureStatuses call (max 256)
+	RollbackAfter time.Duration // how long a signature may be unknown before it is rolled back
+	MaxAge        time.Duration // rows older than this are left alone (e.g. pre-tracking history)
+}
+
+func New(http *rpc.HTTPClient, st *store.Store, rdb *redis.Client) *Reconciler {
+	return &Reconciler{
+		HTTP:          http,
+		Store:         st,
+		Redis:         rdb,
+		Interval:      15 * time.Second,
+		Batch:         256,
+		RollbackAfter: 5 * time.Minute,
+		MaxAge:        time.Hour,
+	}
+}
+
+// Run reconciles until ctx is done.
+func (r *Reconciler) Run(ctx context.Context) error {
+	t := time.NewTicker(r.Interval)
+	defer t.Stop()
+	for {
+		for {
+			checked, changed, err := r.Once(ctx)
+			if err != nil {
+				log.Printf("[reconcile] %v", err)
+			}
+			// keep going while a full batch is still making progress
+			if err != nil || checked < r.Batch || changed == 0 {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Once checks one batch of pending signatures and returns how many it looked
+// at and how many changed.
+func (r *Reconciler) Once(ctx context.Context) (checked, changed int, err error) {
+	pending, err := r.Store.ListPendingCommitment(ctx, time.Now().Add(-r.MaxAge), r.Batch)
+	if err != nil || len(pending) == 0 {
+		return 0, 0, err
+	}
+	sigs := make([]string, len(pending))
+	for i, p := range pending {
+		sigs[i] = p.Signature
+	}
+	statuses, err := r.HTTP.GetSignatureStatuses(ctx, sigs, true)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	now := time.Now()
+	for i, p := range pending {
+		var st *rpc.SignatureStatus
+		if i < len(statuses) {
+			st = statuses[i]
+		}
+
+		to, slot := p.Commitment, p.Slot
+		switch {
+		case st == nil:
+			if now.Sub(p.CreatedAt) < r.RollbackAfter {
+				continue // may just not have propagated yet
+			}
+			to = store.CommitmentRolledBack
+		case st.ConfirmationStatus == store.CommitmentFinalized,
+			st.ConfirmationStatus == store.CommitmentConfirmed && p.Commitment == store.CommitmentProcessed:
+			to, slot = st.ConfirmationStatus, int64(st.Slot)
+		case int64(st.Slot) != slot:
+			slot = int64(st.Slot) // re-included in another slot after a fork
+		default:
+			continue
+		}
+
+		if err := r.Store.SetCommitment(ctx, p.Signature, slot, to); err != nil {
+			return len(pending), changed, err
+		}
+		changed++
+		r.emit(ctx, p, to, slot)
+	}
+	if changed > 0 {
+		log.Printf("[reconcile] checked=%d changed=%d", len(pending), changed)
+	}
+	return len(pending), changed, nil
+}
+
+func (r *Reconciler) emit(ctx context.Context, p store.PendingTx, to string, slot int64) {
+	if r.Redis == nil {
+		return
+	}
+	if err := r.Redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: StatusStream,
+		Values: map[string]any{
+			"kind":      "status_change",
+			"signature": p.Signature,
+			"slot":      slot,
+			"prev_slot": p.Slot,
+			"from":      p.Commitment,
+			"to":        to,
+			"ts":        time.Now().UTC().Format(time.RFC3339Nano),
+		},
+		MaxLen: 100000,
+	}).Err(); err != nil {
+		log.Printf("[reconcile] xadd %s: %v", StatusStream, err)
+	}
+}
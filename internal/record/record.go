@@ -0,0 +1,183 @@
+package record
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Record is one raw WebSocket notification as it was received. Kind and
+// Address describe the subscription it belonged to, since notifications only
+// carry the node's numeric subscription id.
+type Record struct {
+	At       time.Time       `json:"at"`
+	Endpoint string          `json:"endpoint"`
+	Kind     string          `json:"kind,omitempty"`
+	Address  string          `json:"address,omitempty"`
+	Msg      json.RawMessage `json:"msg"`
+}
+
+// Recorder appends records to gzip-compressed NDJSON files in Dir, starting a
+// new file when the current one reaches MaxBytes (uncompressed) or MaxAge.
+type Recorder struct {
+	Dir      string
+	Prefix   string
+	MaxBytes int64
+	MaxAge   time.Duration
+
+	mu      sync.Mutex
+	f       *os.File
+	gz      *gzip.Writer
+	w       *bufio.Writer
+	written int64
+	opened  time.Time
+	seq     int // keeps names unique when rotating within the same millisecond
+}
+
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Recorder{Dir: dir, Prefix: "ws", MaxBytes: 64 << 20, MaxAge: time.Hour}, nil
+}
+
+// Write appends rec. It is safe for concurrent use; a nil Recorder is a no-op.
+func (r *Recorder) Write(rec Record) error {
+	if r == nil {
+		return nil
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.w == nil || r.written >= r.MaxBytes || time.Since(r.opened) >= r.MaxAge {
+		if err := r.rotateLocked(); err != nil {
+			return err
+		}
+	}
+	n, err := r.w.Write(append(line, '\n'))
+	r.written += int64(n)
+	return err
+}
+
+func (r *Recorder) rotateLocked() error {
+	if err := r.closeLocked(); err != nil {
+		log.Printf("record: close: %v", err)
+	}
+	r.seq++
+	name := filepath.Join(r.Dir, fmt.Sprintf("%s-%s-%04d.ndjson.gz", r.Prefix, time.Now().UTC().Format("20060102T150405.000"), r.seq))
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	r.f, r.gz = f, gzip.NewWriter(f)
+	r.w = bufio.NewWriterSize(r.gz, 64<<10)
+	r.written, r.opened = 0, time.Now()
+	log.Printf("record: writing %s", name)
+	return nil
+}
+
+func (r *Recorder) closeLocked() error {
+	if r.f == nil {
+		return nil
+	}
+	err := errors.Join(r.w.Flush(), r.gz.Close(), r.f.Close())
+	r.f, r.gz, r.w = nil, nil, nil
+	return err
+}
+
+// Close flushes and closes the current file.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeLocked()
+}
+
+// Files expands a glob and sorts the matches by name, which is recording
+// order for files written by Recorder.
+func Files(pattern string) ([]string, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("record: no files match %q", pattern)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Replay reads records from files in order and calls fn for each one.
+// speed 1 reproduces the original gaps between records, 10 plays ten times
+// faster, and 0 plays as fast as possible. A truncated final file (recorder
+// killed mid-write) ends quietly at the last complete record.
+func Replay(ctx context.Context, files []string, speed float64, fn func(Record) error) (int, error) {
+	var n int
+	var prev time.Time
+	for _, name := range files {
+		err := readFile(name, func(rec Record) error {
+			if speed > 0 && !prev.IsZero() && rec.At.After(prev) {
+				wait := time.Duration(float64(rec.At.Sub(prev)) / speed)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+			} else if err := ctx.Err(); err != nil {
+				return err
+			}
+			prev = rec.At
+			n++
+			return fn(rec)
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func readFile(name string, fn func(Record) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	defer gz.Close()
+
+	sc := bufio.NewScanner(gz)
+	sc.Buffer(make([]byte, 1<<20), 16<<20)
+	for sc.Scan() {
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			log.Printf("record: %s: skip bad line: %v", name, err)
+			continue
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
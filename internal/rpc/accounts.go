@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rileyafox/solana-sentinel/pkg/types"
+)
+
+// RPCContext is the context object of responses that carry one.
+type RPCContext struct {
+	Slot uint64 `json:"slot"`
+}
+
+// Response is a result together with the slot the node evaluated it at.
+type Response[T any] struct {
+	Context RPCContext `json:"context"`
+	Value   T          `json:"value"`
+}
+
+// DataSlice limits returned account data to Length bytes from Offset
+// (base58, base64 and base64+zstd encodings only).
+type DataSlice struct {
+	Offset uint64 `json:"offset"`
+	Length uint64 `json:"length"`
+}
+
+// AccountOpts configures the account queries. Empty fields use the node's
+// defaults (base58 data, finalized commitment).
+type AccountOpts struct {
+	Commitment     string     `json:"commitment,omitempty"`
+	Encoding       string     `json:"encoding,omitempty"` // base58|base64|base64+zstd|jsonParsed
+	DataSlice      *DataSlice `json:"dataSlice,omitempty"`
+	MinContextSlot uint64     `json:"minContextSlot,omitempty"`
+}
+
+// KeyedAccount is an account together with its address.
+type KeyedAccount struct {
+	Pubkey  string       `json:"pubkey"`
+	Account AccountValue `json:"account"`
+}
+
+// GetAccountInfoWithOpts fetches one account; Value is nil when it does not exist.
+func (c *HTTPClient) GetAccountInfoWithOpts(ctx context.Context, address string, opts AccountOpts) (*Response[*AccountValue], error) {
+	return rpcDo[*Response[*AccountValue]](ctx, c, "getAccountInfo", []any{address, opts})
+}
+
+// GetMultipleAccounts fetches up to 100 accounts in one call. Value[i] belongs
+// to addresses[i] and is nil for accounts that do not exist.
+func (c *HTTPClient) GetMultipleAccounts(ctx context.Context, addresses []string, opts AccountOpts) (*Response[[]*AccountValue], error) {
+	if len(addresses) > 100 {
+		return nil, fmt.Errorf("getMultipleAccounts: %d addresses (max 100)", len(addresses))
+	}
+	return rpcDo[*Response[[]*AccountValue]](ctx, c, "getMultipleAccounts", []any{addresses, opts})
+}
+
+// ProgramAccountsOpts configures getProgramAccounts. Filters work as for
+// programSubscribe; without them the node returns every account the program
+// owns, which many providers refuse for large programs.
+type ProgramAccountsOpts struct {
+	AccountOpts
+	Filters []ProgramFilter `json:"filters,omitempty"`
+}
+
+// GetProgramAccounts lists the accounts owned by programID that match opts.Filters.
+func (c *HTTPClient) GetProgramAccounts(ctx context.Context, programID string, opts ProgramAccountsOpts) (*Response[[]KeyedAccount], error) {
+	params := []any{programID, struct {
+		ProgramAccountsOpts
+		WithContext bool `json:"withContext"`
+	}{opts, true}}
+	return rpcDo[*Response[[]KeyedAccount]](ctx, c, "getProgramAccounts", params)
+}
+
+// TokenAccountsFilter selects token accounts by Mint or by token ProgramID;
+// set exactly one.
+type TokenAccountsFilter struct {
+	Mint      string `json:"mint,omitempty"`
+	ProgramID string `json:"programId,omitempty"`
+}
+
+// GetTokenAccountsByOwner lists owner's token accounts matching filter.
+// Use Encoding jsonParsed to get decoded balances in the account data.
+func (c *HTTPClient) GetTokenAccountsByOwner(ctx context.Context, owner string, filter TokenAccountsFilter, opts AccountOpts) (*Response[[]KeyedAccount], error) {
+	if (filter.Mint == "") == (filter.ProgramID == "") {
+		return nil, fmt.Errorf("getTokenAccountsByOwner: set exactly one of Mint and ProgramID")
+	}
+	return rpcDo[*Response[[]KeyedAccount]](ctx, c, "getTokenAccountsByOwner", []any{owner, filter, opts})
+}
+
+// TokenAmount is a token balance; see pkg/types.
+type TokenAmount = types.TokenAmount
+
+// GetTokenAccountBalance returns the balance of one SPL token account at
+// commitment ("" = node default).
+func (c *HTTPClient) GetTokenAccountBalance(ctx context.Context, account, commitment string) (*Response[TokenAmount], error) {
+	return rpcDo[*Response[TokenAmount]](ctx, c, "getTokenAccountBalance", []any{account, commitmentOpts(commitment)})
+}
+
+// GetBalance returns an account's lamports at commitment ("" = node default).
+func (c *HTTPClient) GetBalance(ctx context.Context, address, commitment string) (*Response[uint64], error) {
+	return rpcDo[*Response[uint64]](ctx, c, "getBalance", []any{address, commitmentOpts(commitment)})
+}
+
+// commitmentOpts is the config object of methods that only take a commitment.
+func commitmentOpts(commitment string) map[string]any {
+	if commitment == "" {
+		return map[string]any{}
+	}
+	return map[string]any{"commitment": commitment}
+}
@@ -0,0 +1,196 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BatchCall is one request in a Batch. Result must be a pointer; after
+// Batch returns, it holds the decoded result unless Err is set.
+type BatchCall struct {
+	Method string
+	Params any
+	Result any
+	Err    error
+
+	cacheKey string // set by Batch when the result goes into c.Cache
+}
+
+// errBatchRefused means the provider would not take a batch of this size.
+var errBatchRefused = errors.New("batch refused")
+
+// Batch sends calls as JSON-RPC batches of at most MaxBatch requests and
+// fills in each call's Result or Err. Replies are matched by ID, so the
+// provider may answer in any order. A batch the provider refuses (413, or a
+// single error object instead of an array) is split in half and retried.
+// The returned error is the first whole-batch failure (transport, 5xx after
+// retries, unreadable reply); its calls carry it as their Err too.
+// Calls found in c.Cache are answered from it and not sent.
+func (c *HTTPClient) Batch(ctx context.Context, calls []*BatchCall) error {
+	calls = c.batchFromCache(ctx, calls)
+	size := c.MaxBatch
+	if size <= 0 {
+		size = 100
+	}
+	if learned := int(c.batchCap.Load()); learned > 0 {
+		size = min(size, learned)
+	}
+	var first error
+	for start := 0; start < len(calls); start += size {
+		end := min(start+size, len(calls))
+		if err := c.batchChunk(ctx, calls[start:end]); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// batchFromCache fills in the calls c.Cache can answer and returns the rest.
+func (c *HTTPClient) batchFromCache(ctx context.Context, calls []*BatchCall) []*BatchCall {
+	if c.Cache == nil {
+		return calls
+	}
+	rest := make([]*BatchCall, 0, len(calls))
+	for _, call := range calls {
+		key, ok := c.cacheKey(call.Method, call.Params)
+		if !ok {
+			rest = append(rest, call)
+			continue
+		}
+		if raw, hit := c.cacheGet(ctx, call.Method, key); hit {
+			if call.Result != nil {
+				call.Err = json.Unmarshal(raw, call.Result)
+			}
+			continue
+		}
+		call.cacheKey = key
+		rest = append(rest, call)
+	}
+	return rest
+}
+
+func (c *HTTPClient) batchChunk(ctx context.Context, calls []*BatchCall) error {
+	err := c.sendBatch(ctx, calls)
+	if errors.Is(err, errBatchRefused) {
+		if len(calls) == 1 {
+			// The provider takes no batches at all: send it on its own.
+			c.batchCap.Store(1)
+			raw, err := rpcCall[json.RawMessage](ctx, c, calls[0].Method, calls[0].Params)
+			calls[0].Err = err
+			if err == nil && calls[0].cacheKey != "" {
+				c.cachePut(ctx, calls[0].cacheKey, raw)
+			}
+			if err == nil && calls[0].Result != nil {
+				calls[0].Err = json.Unmarshal(raw, calls[0].Result)
+			}
+			return nil
+		}
+		// Later batches start at the size that was not refused.
+		half := len(calls) / 2
+		c.batchCap.Store(int32(half))
+		return errors.Join(c.batchChunk(ctx, calls[:half]), c.batchChunk(ctx, calls[half:]))
+	}
+	if err != nil {
+		for _, call := range calls {
+			call.Err = err
+		}
+	}
+	return err
+}
+
+func (c *HTTPClient) sendBatch(ctx context.Context, calls []*BatchCall) error {
+	reqs := make([]rpcRequest, len(calls))
+	for i, call := range calls {
+		reqs[i] = rpcRequest{JSONRPC: "2.0", ID: i + 1, Method: call.Method, Params: call.Params}
+	}
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return err
+	}
+
+	credits := 0.0
+	for _, call := range calls {
+		credits += c.Limiter.cost(call.Method)
+	}
+	return c.post(ctx, "batch", credits, body, func(res *http.Response) error {
+		if res.StatusCode == http.StatusRequestEntityTooLarge {
+			return fmt.Errorf("%w: %s", errBatchRefused, res.Status)
+		}
+		raw, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '{' {
+			// One error for the whole batch: too large, or batches unsupported.
+			var single rpcResponse[json.RawMessage]
+			if err := json.Unmarshal(raw, &single); err != nil {
+				return err
+			}
+			if single.Error != nil {
+				return fmt.Errorf("%w: %s", errBatchRefused, single.Error.Message)
+			}
+			return fmt.Errorf("rpc batch: unexpected single reply")
+		}
+		var replies []rpcResponse[json.RawMessage]
+		if err := json.Unmarshal(raw, &replies); err != nil {
+			return err
+		}
+		seen := make([]bool, len(calls))
+		for _, r := range replies {
+			i := r.ID - 1
+			if i < 0 || i >= len(calls) || seen[i] {
+				continue
+			}
+			seen[i] = true
+			call := calls[i]
+			switch {
+			case r.Error != nil:
+				call.Err = r.Error
+				continue
+			case call.Result != nil:
+				call.Err = json.Unmarshal(r.Result, call.Result)
+			}
+			if call.Err == nil && call.cacheKey != "" {
+				c.cachePut(ctx, call.cacheKey, r.Result)
+			}
+		}
+		for i, ok := range seen {
+			if !ok {
+				calls[i].Err = fmt.Errorf("rpc batch: no reply for %s (id %d)", calls[i].Method, i+1)
+			}
+		}
+		return nil
+	})
+}
+
+// GetTransactions fetches many transactions in batches. txs[i] and errs[i]
+// belong to sigs[i]; txs[i] is nil when the node does not have it.
+func (c *HTTPClient) GetTransactions(ctx context.Context, sigs []string) ([]*GetTransactionResult, []error) {
+	return c.GetTransactionsAt(ctx, sigs, "")
+}
+
+// GetTransactionsAt is GetTransactions at commitment ("" = confirmed).
+// Finalized results can be served from c.Cache.
+func (c *HTTPClient) GetTransactionsAt(ctx context.Context, sigs []string, commitment string) ([]*GetTransactionResult, []error) {
+	opts := c.transactionOpts()
+	if commitment != "" {
+		opts.Commitment = commitment
+	}
+	txs := make([]*GetTransactionResult, len(sigs))
+	calls := make([]*BatchCall, len(sigs))
+	for i, sig := range sigs {
+		calls[i] = &BatchCall{Method: "getTransaction", Params: []any{sig, opts}, Result: &txs[i]}
+	}
+	_ = c.Batch(ctx, calls) // whole-batch failures are also in each call's Err
+	errs := make([]error, len(sigs))
+	for i, call := range calls {
+		errs[i] = call.Err
+	}
+	return txs, errs
+}
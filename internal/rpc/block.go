@@ -0,0 +1,61 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/rileyafox/solana-sentinel/pkg/types"
+)
+
+// Block is a getBlock / blockSubscribe result with full transactions in any
+// encoding (base58/base64 ones are decoded locally).
+type Block struct {
+	Blockhash         string    `json:"blockhash"`
+	PreviousBlockhash string    `json:"previousBlockhash"`
+	ParentSlot        uint64    `json:"parentSlot"`
+	BlockTime         *int64    `json:"blockTime"`
+	BlockHeight       *uint64   `json:"blockHeight"`
+	Transactions      []BlockTx `json:"transactions"`
+	Signatures        []string  `json:"signatures"` // transactionDetails=signatures only
+}
+
+// BlockTx is one transaction of a block, in block order; see pkg/types.
+type BlockTx = types.TransactionWithMeta
+
+// GetBlock fetches a block with full transactions and logs. commitment must be
+// confirmed or finalized (getBlock does not serve processed).
+func (c *HTTPClient) GetBlock(ctx context.Context, slot uint64, commitment string) (*Block, error) {
+	no := false
+	encoding := "json"
+	if c.TxEncoding != "" {
+		encoding = c.TxEncoding
+	}
+	return c.GetBlockWithOpts(ctx, slot, BlockOpts{
+		Encoding:                       encoding,
+		TransactionDetails:             "full",
+		Rewards:                        &no,
+		Commitment:                     commitment,
+		MaxSupportedTransactionVersion: &txVersion0,
+	})
+}
+
+// BlockOpts configures getBlock. Block.Transactions is filled for
+// TransactionDetails full with Encoding json; Block.Signatures for
+// TransactionDetails signatures.
+type BlockOpts struct {
+	Encoding                       string `json:"encoding,omitempty"`           // json|jsonParsed|base58|base64
+	TransactionDetails             string `json:"transactionDetails,omitempty"` // full|accounts|signatures|none
+	Rewards                        *bool  `json:"rewards,omitempty"`
+	Commitment                     string `json:"commitment,omitempty"`
+	MaxSupportedTransactionVersion *int   `json:"maxSupportedTransactionVersion,omitempty"`
+}
+
+// GetBlockWithOpts is GetBlock with explicit options.
+func (c *HTTPClient) GetBlockWithOpts(ctx context.Context, slot uint64, opts BlockOpts) (*Block, error) {
+	return rpcDo[*Block](WithSlot(ctx, slot), c, "getBlock", []any{slot, opts})
+}
+
+// GetBlocks lists the slots in [start, end] that produced a block (skipped
+// slots are left out).
+func (c *HTTPClient) GetBlocks(ctx context.Context, start, end uint64, commitment string) ([]uint64, error) {
+	return rpcDo[[]uint64](WithSlot(ctx, start), c, "getBlocks", []any{start, end, map[string]any{"commitment": commitment}})
+}
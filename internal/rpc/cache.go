@@ -0,0 +1,85 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Cache stores raw results of immutable RPC queries; see internal/rpccache.
+// Implementations must be safe for concurrent use and treat failures as
+// misses: the cache only ever saves requests.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Put(ctx context.Context, key string, value []byte)
+}
+
+// cacheableMethods answer the same for the same params once the data is
+// finalized. Anything that reads current state (getSlot, getAccountInfo,
+// getSignatureStatuses, ...) must never be listed here.
+var cacheableMethods = map[string]bool{
+	"getTransaction": true,
+	"getBlock":       true,
+}
+
+// cacheKey returns the key a call is cached under, or false when its result
+// may still change: a method not in cacheableMethods, or a commitment other
+// than finalized. The node's default commitment is finalized, but a client
+// may be pointed at a node configured otherwise, so it must be explicit.
+func (c *HTTPClient) cacheKey(method string, params any) (string, bool) {
+	if c.Cache == nil || !cacheableMethods[method] {
+		return "", false
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+	var args []json.RawMessage
+	if json.Unmarshal(raw, &args) != nil || len(args) < 2 {
+		return "", false
+	}
+	var cfg struct {
+		Commitment string `json:"commitment"`
+	}
+	if json.Unmarshal(args[len(args)-1], &cfg) != nil || cfg.Commitment != "finalized" {
+		return "", false
+	}
+	sum := sha256.Sum256(raw)
+	return method + ":" + hex.EncodeToString(sum[:]), true
+}
+
+// cacheGet looks key up and reports the outcome to OnCache.
+func (c *HTTPClient) cacheGet(ctx context.Context, method, key string) ([]byte, bool) {
+	raw, ok := c.Cache.Get(ctx, key)
+	if h := c.Hooks.OnCache; h != nil {
+		h(method, ok)
+	}
+	return raw, ok
+}
+
+// cachePut stores a result unless it is null: a transaction or block the
+// node does not have (yet) may well turn up later.
+func (c *HTTPClient) cachePut(ctx context.Context, key string, raw json.RawMessage) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return
+	}
+	c.Cache.Put(ctx, key, raw)
+}
+
+// cachedDo is rpcDo for cacheable calls: the raw result is served from, or
+// stored in, c.Cache and then decoded into T.
+func cachedDo[T any](ctx context.Context, c *HTTPClient, key, method string, params any) (T, error) {
+	var out T
+	raw, ok := c.cacheGet(ctx, method, key)
+	if !ok {
+		var err error
+		if raw, err = rpcCall[json.RawMessage](ctx, c, method, params); err != nil {
+			return out, err
+		}
+		c.cachePut(ctx, key, raw)
+	}
+	err := json.Unmarshal(raw, &out)
+	return out, err
+}
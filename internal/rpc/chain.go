@@ -0,0 +1,54 @@
+package rpc
+
+import "context"
+
+// GetBlockTime returns the estimated production time of slot as a Unix
+// timestamp; nil when the node has no time for it.
+func (c *HTTPClient) GetBlockTime(ctx context.Context, slot uint64) (*int64, error) {
+	return rpcDo[*int64](WithSlot(ctx, slot), c, "getBlockTime", []any{slot})
+}
+
+// LatestBlockhash is a recent blockhash and the last block height at which a
+// transaction using it is still valid.
+type LatestBlockhash struct {
+	Blockhash            string `json:"blockhash"`
+	LastValidBlockHeight uint64 `json:"lastValidBlockHeight"`
+}
+
+// GetLatestBlockhash returns the newest blockhash at commitment ("" = node default).
+func (c *HTTPClient) GetLatestBlockhash(ctx context.Context, commitment string) (*Response[LatestBlockhash], error) {
+	return rpcDo[*Response[LatestBlockhash]](ctx, c, "getLatestBlockhash", []any{commitmentOpts(commitment)})
+}
+
+// EpochInfo is the node's view of the current epoch.
+type EpochInfo struct {
+	AbsoluteSlot     uint64  `json:"absoluteSlot"`
+	BlockHeight      uint64  `json:"blockHeight"`
+	Epoch            uint64  `json:"epoch"`
+	SlotIndex        uint64  `json:"slotIndex"`
+	SlotsInEpoch     uint64  `json:"slotsInEpoch"`
+	TransactionCount *uint64 `json:"transactionCount"`
+}
+
+// GetEpochInfo returns the current epoch at commitment ("" = node default).
+func (c *HTTPClient) GetEpochInfo(ctx context.Context, commitment string) (*EpochInfo, error) {
+	return rpcDo[*EpochInfo](ctx, c, "getEpochInfo", []any{commitmentOpts(commitment)})
+}
+
+// PrioritizationFee is the lowest priority fee (micro-lamports per compute
+// unit) a transaction paid to land in Slot.
+type PrioritizationFee struct {
+	Slot              uint64 `json:"slot"`
+	PrioritizationFee uint64 `json:"prioritizationFee"`
+}
+
+// GetRecentPrioritizationFees returns fees from the node's recent slots
+// (about 150). With accounts (at most 128), each fee is the lowest paid by
+// transactions that lock all of them as writable.
+func (c *HTTPClient) GetRecentPrioritizationFees(ctx context.Context, accounts []string) ([]PrioritizationFee, error) {
+	params := []any{}
+	if len(accounts) > 0 {
+		params = append(params, accounts)
+	}
+	return rpcDo[[]PrioritizationFee](ctx, c, "getRecentPrioritizationFees", params)
+}
@@ -0,0 +1,391 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WSEndpoint is one WebSocket RPC provider. Lower Priority is preferred while healthy.
+type WSEndpoint struct {
+	URL      string
+	Priority int
+}
+
+// ParseWSEndpoints accepts "url[|priority],url[|priority]" (e.g. "wss://a|0,wss://b|1").
+// Entries without an explicit priority rank in list order.
+func ParseWSEndpoints(s string) []WSEndpoint {
+	var out []WSEndpoint
+	for i, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		ep := WSEndpoint{URL: part, Priority: i}
+		if u, p, ok := strings.Cut(part, "|"); ok {
+			ep.URL = strings.TrimSpace(u)
+			if n, err := strconv.Atoi(strings.TrimSpace(p)); err == nil {
+				ep.Priority = n
+			}
+		}
+		out = append(out, ep)
+	}
+	return out
+}
+
+// EndpointLabel strips the query string (where providers put API keys) so a
+// URL is safe to use as a metric label.
+func EndpointLabel(raw string) string {
+	if u, err := neturl.Parse(raw); err == nil {
+		u.RawQuery, u.User = "", nil
+		return u.String()
+	}
+	return raw
+}
+
+// Scoring knobs. Scores run 0..100; below healthyScore an endpoint is only
+// used when nothing better is available.
+const (
+	healthyScore       = 50.0
+	failurePenalty     = 20.0            // per (decayed) connect failure
+	failureHalfLife    = 2 * time.Minute // how fast old failures are forgiven
+	maxLatencyPenalty  = 30.0
+	latencyPenaltyUnit = 20 * time.Millisecond // 1 point per unit behind the fastest endpoint
+	maxLagPenalty      = 40.0
+	lagPenaltyPerSlot  = 2.0
+	failbackStreak     = 3 // consecutive good probes before failing back
+)
+
+type endpointState struct {
+	WSEndpoint
+	failures float64 // decayed count
+	failedAt time.Time
+	latency  time.Duration // EWMA of notification delay vs the fastest endpoint
+	lastSlot uint64
+	streak   int // consecutive successful probes
+	probed   bool
+}
+
+func (e *endpointState) decayedFailures(now time.Time) float64 {
+	if e.failures == 0 {
+		return 0
+	}
+	half := now.Sub(e.failedAt).Seconds() / failureHalfLife.Seconds()
+	return e.failures * math.Pow(0.5, half)
+}
+
+func (e *endpointState) score(now time.Time, head uint64) float64 {
+	s := 100.0
+	s -= failurePenalty * e.decayedFailures(now)
+	s -= math.Min(maxLatencyPenalty, float64(e.latency)/float64(latencyPenaltyUnit))
+	if head > 0 && e.lastSlot > 0 && head > e.lastSlot {
+		s -= math.Min(maxLagPenalty, float64(head-e.lastSlot)*lagPenaltyPerSlot)
+	}
+	return math.Max(0, s)
+}
+
+// EndpointScore is a point-in-time view of one endpoint, for metrics and logs.
+type EndpointScore struct {
+	URL             string
+	Priority        int
+	Score           float64
+	Healthy         bool
+	Active          bool
+	ConnectFailures float64
+	Latency         time.Duration
+	SlotLag         uint64
+}
+
+// EndpointPool scores a set of WebSocket endpoints on connect failures,
+// notification latency and slot freshness, and decides which one to use.
+// Probing (slotSubscribe on every endpoint) feeds latency and freshness and
+// lets a recovered primary win back traffic.
+type EndpointPool struct {
+	ProbeInterval time.Duration
+	ProbeWindow   time.Duration // how long each probe listens for slot notifications
+
+	mu     sync.Mutex
+	eps    []*endpointState
+	active string
+	head   uint64
+
+	dialer    *websocket.Dialer
+	probeOnce sync.Once
+}
+
+func NewEndpointPool(eps []WSEndpoint) *EndpointPool {
+	p := &EndpointPool{
+		ProbeInterval: 15 * time.Second,
+		ProbeWindow:   3 * time.Second,
+		dialer:        &websocket.Dialer{HandshakeTimeout: 10 * time.Second},
+	}
+	for _, ep := range eps {
+		p.eps = append(p.eps, &endpointState{WSEndpoint: ep})
+	}
+	sort.SliceStable(p.eps, func(i, j int) bool { return p.eps[i].Priority < p.eps[j].Priority })
+	return p
+}
+
+func (p *EndpointPool) Len() int { return len(p.eps) }
+
+// Endpoints returns the configured endpoints in priority order.
+func (p *EndpointPool) Endpoints() []WSEndpoint {
+	out := make([]WSEndpoint, len(p.eps))
+	for i, e := range p.eps {
+		out[i] = e.WSEndpoint
+	}
+	return out
+}
+
+// Pick returns the endpoint to connect to next and marks it active.
+func (p *EndpointPool) Pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	best := p.bestLocked(time.Now())
+	if best == nil {
+		return ""
+	}
+	if p.active != "" && p.active != best.URL {
+		log.Printf("ws: switching endpoint %s -> %s", p.active, best.URL)
+	}
+	p.active = best.URL
+	return best.URL
+}
+
+// Active returns the endpoint most recently handed out by Pick.
+func (p *EndpointPool) Active() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.active
+}
+
+func (p *EndpointPool) bestLocked(now time.Time) *endpointState {
+	var best *endpointState
+	var bestScore float64
+	for _, e := range p.eps {
+		s := e.score(now, p.head)
+		if best == nil || better(e, s, best, bestScore) {
+			best, bestScore = e, s
+		}
+	}
+	return best
+}
+
+// better prefers healthy over unhealthy, then priority, then score.
+func better(a *endpointState, as float64, b *endpointState, bs float64) bool {
+	ah, bh := as >= healthyScore, bs >= healthyScore
+	if ah != bh {
+		return ah
+	}
+	if !ah {
+		return as > bs
+	}
+	if a.Priority != b.Priority {
+		return a.Priority < b.Priority
+	}
+	return as > bs
+}
+
+// SwitchTarget reports whether a live connection to current should be
+// dropped: either current became unhealthy and something better exists, or a
+// higher-priority endpoint has passed enough consecutive probes to fail back.
+func (p *EndpointPool) SwitchTarget(current string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	best := p.bestLocked(now)
+	cur := p.findLocked(current)
+	if best == nil || cur == nil || best == cur {
+		return "", false
+	}
+	if cur.score(now, p.head) < healthyScore {
+		return best.URL, true
+	}
+	if best.Priority < cur.Priority && best.streak >= failbackStreak {
+		return best.URL, true
+	}
+	return "", false
+}
+
+// ReportFailure records a failed dial or a dropped connection.
+func (p *EndpointPool) ReportFailure(url string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e := p.findLocked(url); e != nil {
+		now := time.Now()
+		e.failures = e.decayedFailures(now) + 1
+		e.failedAt = now
+		e.streak = 0
+	}
+}
+
+func (p *EndpointPool) findLocked(url string) *endpointState {
+	for _, e := range p.eps {
+		if e.URL == url {
+			return e
+		}
+	}
+	return nil
+}
+
+// Scores returns the current view of every endpoint.
+func (p *EndpointPool) Scores() []EndpointScore {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	out := make([]EndpointScore, 0, len(p.eps))
+	for _, e := range p.eps {
+		s := e.score(now, p.head)
+		var lag uint64
+		if p.head > e.lastSlot && e.lastSlot > 0 {
+			lag = p.head - e.lastSlot
+		}
+		out = append(out, EndpointScore{
+			URL:             e.URL,
+			Priority:        e.Priority,
+			Score:           s,
+			Healthy:         s >= healthyScore,
+			Active:          e.URL == p.active,
+			ConnectFailures: e.decayedFailures(now),
+			Latency:         e.latency,
+			SlotLag:         lag,
+		})
+	}
+	return out
+}
+
+// StartProbing launches the background prober once. It is a no-op for a
+// single endpoint, where there is nothing to compare or fail over to.
+func (p *EndpointPool) StartProbing(ctx context.Context) {
+	if len(p.eps) < 2 {
+		return
+	}
+	p.probeOnce.Do(func() { go p.probeLoop(ctx) })
+}
+
+func (p *EndpointPool) probeLoop(ctx context.Context) {
+	t := time.NewTicker(p.ProbeInterval)
+	defer t.Stop()
+	for {
+		p.probeRound(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// probeRound subscribes to slots on every endpoint at once and compares when
+// each one delivered the same slot. The earliest delivery is the reference
+// for latency; the highest slot seen is the chain head for freshness.
+func (p *EndpointPool) probeRound(ctx context.Context) {
+	type result struct {
+		url  string
+		seen map[uint64]time.Time
+		err  error
+	}
+	results := make(chan result, len(p.eps))
+	for _, e := range p.eps {
+		go func(url string) {
+			seen, err := p.probe(ctx, url)
+			results <- result{url: url, seen: seen, err: err}
+		}(e.URL)
+	}
+
+	first := map[uint64]time.Time{}
+	all := make([]result, 0, len(p.eps))
+	for range p.eps {
+		r := <-results
+		all = append(all, r)
+		for slot, at := range r.seen {
+			if f, ok := first[slot]; !ok || at.Before(f) {
+				first[slot] = at
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for slot := range first {
+		if slot > p.head {
+			p.head = slot
+		}
+	}
+	for _, r := range all {
+		e := p.findLocked(r.url)
+		if r.err != nil || len(r.seen) == 0 {
+			e.failures = e.decayedFailures(now) + 1
+			e.failedAt = now
+			e.streak = 0
+			continue
+		}
+		var total time.Duration
+		var maxSlot uint64
+		for slot, at := range r.seen {
+			total += at.Sub(first[slot])
+			if slot > maxSlot {
+				maxSlot = slot
+			}
+		}
+		delay := total / time.Duration(len(r.seen))
+		if e.probed {
+			e.latency = (e.latency*7 + delay*3) / 10
+		} else {
+			e.latency, e.probed = delay, true
+		}
+		e.lastSlot = maxSlot
+		e.streak++
+	}
+}
+
+// probe listens to slotNotification on url for ProbeWindow and returns the
+// receive time of every slot.
+func (p *EndpointPool) probe(ctx context.Context, url string) (map[uint64]time.Time, error) {
+	dctx, cancel := context.WithTimeout(ctx, p.ProbeWindow+p.dialer.HandshakeTimeout)
+	defer cancel()
+	conn, _, err := p.dialer.DialContext(dctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "slotSubscribe"}); err != nil {
+		return nil, err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(p.ProbeWindow))
+
+	seen := map[uint64]time.Time{}
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			// the read deadline ending the window is the normal exit
+			if len(seen) > 0 {
+				return seen, nil
+			}
+			return nil, err
+		}
+		at := time.Now()
+		var msg struct {
+			Method string `json:"method"`
+			Params struct {
+				Result struct {
+					Slot uint64 `json:"slot"`
+				} `json:"result"`
+			} `json:"params"`
+		}
+		if json.Unmarshal(data, &msg) == nil && msg.Method == "slotNotification" {
+			seen[msg.Params.Result.Slot] = at
+		}
+	}
+}
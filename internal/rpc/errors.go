@@ -0,0 +1,128 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Solana JSON-RPC server error codes (see the validator's rpc_custom_error.rs).
+const (
+	CodeBlockCleanedUp             = -32001
+	CodePreflightFailure           = -32002
+	CodeBlockNotAvailable          = -32004
+	CodeNodeUnhealthy              = -32005
+	CodeSlotSkipped                = -32007
+	CodeNoSnapshot                 = -32008
+	CodeLongTermStorageSlotSkipped = -32009
+	CodeTxHistoryNotAvailable      = -32011
+	CodeBlockStatusNotAvailableYet = -32014
+	CodeUnsupportedTxVersion       = -32015
+	CodeMinContextSlotNotReached   = -32016
+
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternal       = -32603
+
+	// Some providers put the HTTP status in the JSON-RPC error code.
+	CodeRateLimited = 429
+)
+
+// RPCError is a failed JSON-RPC call: either an error object from the node
+// (Code, Message, Data) or an HTTP status the client gave up on (HTTPStatus,
+// with Code 0).
+type RPCError struct {
+	Code       int             `json:"code"`
+	Message    string          `json:"message"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	HTTPStatus int             `json:"-"`
+}
+
+func (e *RPCError) Error() string {
+	if e.Code == 0 && e.HTTPStatus != 0 {
+		return fmt.Sprintf("rpc http %d: %s", e.HTTPStatus, e.Message)
+	}
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Retryable reports whether the same request may succeed later: the node is
+// behind or still assembling the block, it is rate limiting, or it failed
+// with a 5xx. Skipped slots, pruned history and bad requests are final.
+func (e *RPCError) Retryable() bool {
+	if e.HTTPStatus == http.StatusTooManyRequests || e.HTTPStatus >= 500 {
+		return true
+	}
+	switch e.Code {
+	case CodeBlockNotAvailable, CodeNodeUnhealthy, CodeBlockStatusNotAvailableYet,
+		CodeMinContextSlotNotReached, CodeInternal, CodeRateLimited:
+		return true
+	}
+	return false
+}
+
+func rpcCode(err error) (int, bool) {
+	var e *RPCError
+	if errors.As(err, &e) {
+		return e.Code, true
+	}
+	return 0, false
+}
+
+// IsSlotSkipped reports a slot that has no block: skipped by the leader, or
+// missing from long-term storage.
+func IsSlotSkipped(err error) bool {
+	code, _ := rpcCode(err)
+	return code == CodeSlotSkipped || code == CodeLongTermStorageSlotSkipped
+}
+
+// IsHistoryUnavailable reports a node that no longer has the requested slot
+// or transaction; an archive node may still have it.
+func IsHistoryUnavailable(err error) bool {
+	code, _ := rpcCode(err)
+	switch code {
+	case CodeBlockCleanedUp, CodeLongTermStorageSlotSkipped, CodeTxHistoryNotAvailable, CodeNoSnapshot:
+		return true
+	}
+	return false
+}
+
+// IsNodeBehind reports a node that is unhealthy or has not reached the
+// requested slot yet.
+func IsNodeBehind(err error) bool {
+	code, _ := rpcCode(err)
+	return code == CodeNodeUnhealthy || code == CodeMinContextSlotNotReached
+}
+
+// IsBlockNotAvailable reports a block that exists but is not ready yet.
+func IsBlockNotAvailable(err error) bool {
+	code, _ := rpcCode(err)
+	return code == CodeBlockNotAvailable || code == CodeBlockStatusNotAvailableYet
+}
+
+// IsRateLimited reports a 429, as an HTTP status or a JSON-RPC error code.
+func IsRateLimited(err error) bool {
+	var e *RPCError
+	return errors.As(err, &e) && (e.HTTPStatus == http.StatusTooManyRequests || e.Code == CodeRateLimited)
+}
+
+// IsMethodNotFound reports a method the node does not serve.
+func IsMethodNotFound(err error) bool {
+	code, _ := rpcCode(err)
+	return code == CodeMethodNotFound
+}
+
+// IsRetryable reports whether err is worth retrying: a retryable RPCError or
+// a transport failure. Context cancellation is not.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var e *RPCError
+	if errors.As(err, &e) {
+		return e.Retryable()
+	}
+	return !errors.Is(err, errBatchRefused)
+}
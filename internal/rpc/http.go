@@ -5,15 +5,32 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"math/rand"
 	"net/http"
+	"os"
+	"sync/atomic"
 	"time"
+
+	"github.com/rileyafox/solana-sentinel/pkg/types"
 )
 
 type HTTPClient struct {
 	BaseURL    string
 	HTTP       *http.Client
 	MaxRetries int
+	MaxBatch   int // requests per batch POST; Batch halves it further if the provider refuses
+	Limiter    *Limiter
+	Hooks      HTTPHooks
+	// TxEncoding is the transaction encoding GetTransaction(s) and GetBlock
+	// ask for: "" keeps jsonParsed and json; base64 moves decoding to this
+	// side (types.DecodeTransaction), which is cheaper for providers.
+	TxEncoding string
+	// Cache, when set, keeps finalized getTransaction and getBlock results
+	// so that repeated queries (backfill reruns, reindexing) skip the node.
+	Cache Cache
+
+	batchCap atomic.Int32 // largest batch size not refused so far (0 = none refused)
+	pool     *HTTPPool    // set by NewHTTPPool; calls are routed over its endpoints
 }
 
 func NewHTTPClient(base string) *HTTPClient {
@@ -21,6 +38,10 @@ func NewHTTPClient(base string) *HTTPClient {
 		BaseURL:    base,
 		HTTP:       &http.Client{Timeout: 15 * time.Second},
 		MaxRetries: 3,
+		MaxBatch:   100,
+		Limiter:    LimiterFor(base),
+		Hooks:      DefaultHTTPHooks,
+		TxEncoding: os.Getenv("RPC_TX_ENCODING"),
 	}
 }
 
@@ -31,21 +52,25 @@ type rpcRequest struct {
 	Params  interface{} `json:"params,omitempty"`
 }
 
-type rpcError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
-
 type rpcResponse[T any] struct {
 	JSONRPC string    `json:"jsonrpc"`
 	ID      int       `json:"id"`
 	Result  T         `json:"result"`
-	Error   *rpcError `json:"error,omitempty"`
+	Error   *RPCError `json:"error,omitempty"`
 }
 
 // Standalone generic function (allowed): makes the JSON-RPC call and decodes Result into T.
+// Immutable queries go through c.Cache when one is set.
 func rpcDo[T any](ctx context.Context, c *HTTPClient, method string, params any) (T, error) {
-	var zero T
+	if key, ok := c.cacheKey(method, params); ok {
+		return cachedDo[T](ctx, c, key, method, params)
+	}
+	return rpcCall[T](ctx, c, method, params)
+}
+
+// rpcCall is rpcDo without the cache.
+func rpcCall[T any](ctx context.Context, c *HTTPClient, method string, params any) (T, error) {
+	var out T
 
 	body, _ := json.Marshal(rpcRequest{
 		JSONRPC: "2.0",
@@ -54,80 +79,157 @@ func rpcDo[T any](ctx context.Context, c *HTTPClient, method string, params any)
 		Params:  params,
 	})
 
+	err := c.post(ctx, method, c.Limiter.cost(method), body, func(res *http.Response) error {
+		var wrapper rpcResponse[T]
+		if err := json.NewDecoder(res.Body).Decode(&wrapper); err != nil {
+			return err
+		}
+		if wrapper.Error != nil {
+			return wrapper.Error
+		}
+		out = wrapper.Result
+		return nil
+	})
+	return out, err
+}
+
+// post sends a JSON-RPC body and hands the reply to decode. Each attempt
+// first waits for credits in the endpoint's limiter. Transport errors, 5xx
+// replies and retryable RPCErrors from decode are retried with a growing
+// pause; 429s are retried after the
+// provider's Retry-After (or a jittered backoff), pausing every other caller
+// of the endpoint as well.
+func (c *HTTPClient) post(ctx context.Context, method string, credits float64, body []byte, decode func(*http.Response) error) error {
+	if c.pool != nil {
+		return c.pool.post(ctx, c, method, credits, body, decode)
+	}
+	label := EndpointLabel(c.BaseURL)
 	var lastErr error
 	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		waited, err := c.Limiter.Wait(ctx, credits)
+		if c.Hooks.OnWait != nil {
+			c.Hooks.OnWait(label, method, waited)
+		}
+		if err != nil {
+			return err
+		}
+
 		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL, bytes.NewReader(body))
 		if err != nil {
-			return zero, err
+			return err
 		}
 		req.Header.Set("Content-Type", "application/json")
 
+		pause := time.Duration(attempt+1) * 400 * time.Millisecond
 		res, err := c.HTTP.Do(req)
 		if err != nil {
 			lastErr = err
+		} else if res.StatusCode == http.StatusTooManyRequests {
+			res.Body.Close()
+			lastErr = &RPCError{HTTPStatus: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+			if c.Hooks.OnThrottled != nil {
+				c.Hooks.OnThrottled(label, method)
+			}
+			pause = retryAfter(res.Header)
+			if pause <= 0 {
+				pause = jitter(500 * time.Millisecond << attempt)
+			}
+			c.Limiter.PauseUntil(time.Now().Add(pause))
+			continue // the limiter waits out the pause
+		} else if res.StatusCode >= 500 {
+			res.Body.Close()
+			lastErr = &RPCError{HTTPStatus: res.StatusCode, Message: http.StatusText(res.StatusCode)}
 		} else {
-			defer res.Body.Close()
-			if res.StatusCode >= 500 {
-				lastErr = fmt.Errorf("rpc server %s", res.Status)
-			} else {
-				var wrapper rpcResponse[T]
-				if err := json.NewDecoder(res.Body).Decode(&wrapper); err != nil {
-					return zero, err
-				}
-				if wrapper.Error != nil {
-					return zero, errors.New(wrapper.Error.Message)
-				}
-				return wrapper.Result, nil
+			err := decode(res)
+			res.Body.Close()
+			var rerr *RPCError
+			if !errors.As(err, &rerr) || !rerr.Retryable() {
+				return err
 			}
+			lastErr = err // node behind, block not ready yet, ...
 		}
 		// simple backoff
 		select {
-		case <-time.After(time.Duration(attempt+1) * 400 * time.Millisecond):
+		case <-time.After(pause):
 		case <-ctx.Done():
-			return zero, ctx.Err()
+			return ctx.Err()
 		}
 	}
-	return zero, lastErr
+	return lastErr
+}
+
+// jitter returns a random duration in [d/2, d].
+func jitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
 }
 
 type SignatureInfo struct {
-	Signature string  `json:"signature"`
-	Slot      uint64  `json:"slot"`
-	Err       any     `json:"err"`
-	BlockTime *int64  `json:"blockTime"`
-	Memo      *string `json:"memo"`
+	Signature          string  `json:"signature"`
+	Slot               uint64  `json:"slot"`
+	Err                any     `json:"err"`
+	BlockTime          *int64  `json:"blockTime"`
+	Memo               *string `json:"memo"`
+	ConfirmationStatus string  `json:"confirmationStatus"`
 }
 
 func (c *HTTPClient) GetSignaturesForAddress(ctx context.Context, address string, limit int, before string) ([]SignatureInfo, error) {
-	if limit <= 0 || limit > 1000 {
-		limit = 100
-	}
-	type opts struct {
-		Limit  int    `json:"limit,omitempty"`
-		Before string `json:"before,omitempty"`
+	return c.GetSignaturesForAddressWithOpts(ctx, address, SignaturesOpts{Limit: limit, Before: before})
+}
+
+// SignaturesOpts pages getSignaturesForAddress: results are newest first,
+// starting below Before and stopping at (excluding) Until.
+type SignaturesOpts struct {
+	Limit      int    `json:"limit,omitempty"`
+	Before     string `json:"before,omitempty"`
+	Until      string `json:"until,omitempty"`
+	Commitment string `json:"commitment,omitempty"` // confirmed (node default) or finalized
+}
+
+func (c *HTTPClient) GetSignaturesForAddressWithOpts(ctx context.Context, address string, opts SignaturesOpts) ([]SignatureInfo, error) {
+	if opts.Limit <= 0 || opts.Limit > 1000 {
+		opts.Limit = 100
 	}
-	params := []any{address, opts{Limit: limit, Before: before}}
+	params := []any{address, opts}
 	return rpcDo[[]SignatureInfo](ctx, c, "getSignaturesForAddress", params)
 }
 
-type GetTransactionResult struct {
-	Slot        uint64                 `json:"slot"`
-	BlockTime   *int64                 `json:"blockTime"`
-	Meta        map[string]any         `json:"meta"`
-	Transaction map[string]any         `json:"transaction"`
-	Version     any                    `json:"version"`
-}
+// GetTransactionResult is the typed getTransaction result; see pkg/types.
+type GetTransactionResult = types.TransactionWithMeta
 
+// GetTransaction fetches a transaction at confirmed, as jsonParsed unless
+// TxEncoding says otherwise; nil when the node does not have it (yet).
 func (c *HTTPClient) GetTransaction(ctx context.Context, signature string) (*GetTransactionResult, error) {
-	params := []any{
-		signature,
-		map[string]any{
-			"encoding":                       "jsonParsed",
-			"maxSupportedTransactionVersion": 0,
-			"commitment":                     "confirmed",
-		},
+	return c.GetTransactionWithOpts(ctx, signature, c.transactionOpts())
+}
+
+// TransactionOpts configures getTransaction. With Encoding json, base58 or
+// base64 the Transaction field of the result holds the undecoded form.
+type TransactionOpts struct {
+	Encoding                       string `json:"encoding,omitempty"` // json|jsonParsed|base58|base64
+	Commitment                     string `json:"commitment,omitempty"`
+	MaxSupportedTransactionVersion *int   `json:"maxSupportedTransactionVersion,omitempty"`
+}
+
+var txVersion0 = 0
+
+var defaultTransactionOpts = TransactionOpts{
+	Encoding:                       "jsonParsed",
+	Commitment:                     "confirmed",
+	MaxSupportedTransactionVersion: &txVersion0,
+}
+
+// GetTransactionWithOpts is GetTransaction with explicit options. Leaving
+// MaxSupportedTransactionVersion nil makes the node reject v0 transactions.
+func (c *HTTPClient) GetTransactionWithOpts(ctx context.Context, signature string, opts TransactionOpts) (*GetTransactionResult, error) {
+	return rpcDo[*GetTransactionResult](ctx, c, "getTransaction", []any{signature, opts})
+}
+
+func (c *HTTPClient) transactionOpts() TransactionOpts {
+	opts := defaultTransactionOpts
+	if c.TxEncoding != "" {
+		opts.Encoding = c.TxEncoding
 	}
-	return rpcDo[*GetTransactionResult](ctx, c, "getTransaction", params)
+	return opts
 }
 
 type AccountInfoResp struct {
@@ -143,17 +245,54 @@ type AccountInfoResp struct {
 	} `json:"value"`
 }
 
+// GetAccountInfo fetches an account as jsonParsed at confirmed. Use
+// GetAccountInfoWithOpts for other encodings or commitments.
 func (c *HTTPClient) GetAccountInfo(ctx context.Context, address string) (*AccountInfoResp, error) {
-	params := []any{
-		address,
-		map[string]any{
-			"encoding":   "jsonParsed",
-			"commitment": "confirmed",
-		},
-	}
+	params := []any{address, AccountOpts{Encoding: "jsonParsed", Commitment: "confirmed"}}
 	return rpcDo[*AccountInfoResp](ctx, c, "getAccountInfo", params)
 }
 
+// SignatureStatus is one entry of getSignatureStatuses; nil entries mean the
+// node does not know the signature.
+type SignatureStatus struct {
+	Slot               uint64  `json:"slot"`
+	Confirmations      *uint64 `json:"confirmations"`
+	Err                any     `json:"err"`
+	ConfirmationStatus string  `json:"confirmationStatus"`
+}
+
+// GetSignatureStatuses looks up to 256 signatures at once. searchHistory asks
+// the node to check the ledger, not just its recent status cache.
+func (c *HTTPClient) GetSignatureStatuses(ctx context.Context, sigs []string, searchHistory bool) ([]*SignatureStatus, error) {
+	params := []any{sigs, map[string]any{"searchTransactionHistory": searchHistory}}
+	res, err := rpcDo[struct {
+		Value []*SignatureStatus `json:"value"`
+	}](ctx, c, "getSignatureStatuses", params)
+	if err != nil {
+		return nil, err
+	}
+	return res.Value, nil
+}
+
+// GetSlot returns the node's latest slot at commitment ("" = node default, finalized).
+func (c *HTTPClient) GetSlot(ctx context.Context, commitment string) (uint64, error) {
+	params := []any{}
+	if commitment != "" {
+		params = append(params, map[string]any{"commitment": commitment})
+	}
+	return rpcDo[uint64](ctx, c, "getSlot", params)
+}
+
+// GetBlockHeight returns the current block height at commitment ("" = node default).
+// Compare it with a blockhash's lastValidBlockHeight to tell whether a transaction expired.
+func (c *HTTPClient) GetBlockHeight(ctx context.Context, commitment string) (uint64, error) {
+	params := []any{}
+	if commitment != "" {
+		params = append(params, map[string]any{"commitment": commitment})
+	}
+	return rpcDo[uint64](ctx, c, "getBlockHeight", params)
+}
+
 // Quick liveness check
 func (c *HTTPClient) Ping(ctx context.Context) error {
 	_, err := rpcDo[uint64](ctx, c, "getSlot", []any{})
@@ -0,0 +1,343 @@
+package rpc
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HTTPEndpoint is one JSON-RPC provider in an HTTP pool.
+type HTTPEndpoint struct {
+	URL     string
+	Weight  int  // share of traffic among healthy endpoints (default 1)
+	Archive bool // keeps full history; calls for old slots go here first
+}
+
+// ParseHTTPEndpoints accepts "url[|weight][|archive],..." (e.g.
+// "https://a|3,https://b|1,https://archive|1|archive").
+func ParseHTTPEndpoints(s string) []HTTPEndpoint {
+	var out []HTTPEndpoint
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(part), "|")
+		if fields[0] == "" {
+			continue
+		}
+		ep := HTTPEndpoint{URL: strings.TrimSpace(fields[0]), Weight: 1}
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if f == "archive" {
+				ep.Archive = true
+			} else if n, err := strconv.Atoi(f); err == nil && n > 0 {
+				ep.Weight = n
+			}
+		}
+		out = append(out, ep)
+	}
+	return out
+}
+
+// HTTPPool spreads calls over several HTTP endpoints by weight. Calls hinted
+// at old slots (WithSlot) or marked PreferArchive go to archive endpoints
+// first. An endpoint that fails EjectAfter times in a row sits out for
+// EjectFor; the next success (or a good health probe) reinstates it. A
+// failed call is retried once on each other endpoint before giving up.
+type HTTPPool struct {
+	RecentSlots   uint64        // slots behind the head still served by every endpoint
+	EjectAfter    int           // consecutive failures before an endpoint is ejected
+	EjectFor      time.Duration // how long an ejected endpoint is skipped
+	ProbeInterval time.Duration // getSlot health probes (StartHealthChecks)
+	MaxLag        uint64        // a probe this far behind the head counts as a failure
+
+	mu      sync.Mutex
+	eps     []*httpEndpoint
+	head    uint64 // highest slot seen by a probe (or learnHead)
+	probing atomic.Bool
+}
+
+type httpEndpoint struct {
+	HTTPEndpoint
+	client       *HTTPClient
+	failures     int
+	ejectedUntil time.Time
+}
+
+// NewHTTPPool returns a client that routes over eps. A single plain endpoint
+// gets an ordinary NewHTTPClient, so a one-URL setting behaves as before.
+// Pool settings come from RPC_ARCHIVE_AFTER_SLOTS, RPC_EJECT_AFTER and
+// RPC_EJECT_SEC.
+func NewHTTPPool(eps []HTTPEndpoint) *HTTPClient {
+	if len(eps) == 1 && !eps[0].Archive {
+		return NewHTTPClient(eps[0].URL)
+	}
+	p := &HTTPPool{
+		RecentSlots:   uint64(envInt("RPC_ARCHIVE_AFTER_SLOTS", 150000)),
+		EjectAfter:    envInt("RPC_EJECT_AFTER", 3),
+		EjectFor:      time.Duration(envInt("RPC_EJECT_SEC", 30)) * time.Second,
+		ProbeInterval: 10 * time.Second,
+		MaxLag:        150,
+	}
+	for _, ep := range eps {
+		c := NewHTTPClient(ep.URL)
+		c.MaxRetries = 1 // the pool fails over instead
+		p.eps = append(p.eps, &httpEndpoint{HTTPEndpoint: ep, client: c})
+	}
+	base := ""
+	if len(eps) > 0 {
+		base = eps[0].URL
+	}
+	return &HTTPClient{
+		BaseURL:    base,
+		HTTP:       &http.Client{Timeout: 15 * time.Second},
+		MaxRetries: 3,
+		MaxBatch:   100,
+		// Rate 0: this limiter only prices calls; each endpoint has its own bucket.
+		Limiter:    &Limiter{Credits: ParseCredits(os.Getenv("RPC_METHOD_CREDITS"))},
+		Hooks:      DefaultHTTPHooks,
+		TxEncoding: os.Getenv("RPC_TX_ENCODING"),
+		pool:       p,
+	}
+}
+
+func envInt(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return def
+}
+
+type ctxKey int
+
+const (
+	slotKey ctxKey = iota
+	archiveKey
+)
+
+// WithSlot tells a pool which slot a call is about, so calls for slots older
+// than RecentSlots behind the head go to archive endpoints.
+func WithSlot(ctx context.Context, slot uint64) context.Context {
+	return context.WithValue(ctx, slotKey, slot)
+}
+
+// PreferArchive sends calls made with ctx to archive endpoints first.
+func PreferArchive(ctx context.Context) context.Context {
+	return context.WithValue(ctx, archiveKey, true)
+}
+
+func (p *HTTPPool) wantsArchive(ctx context.Context) bool {
+	if v, _ := ctx.Value(archiveKey).(bool); v {
+		return true
+	}
+	slot, ok := ctx.Value(slotKey).(uint64)
+	if !ok {
+		return false
+	}
+	p.mu.Lock()
+	head := p.head
+	p.mu.Unlock()
+	if head == 0 {
+		head = p.learnHead(ctx)
+	}
+	return head > p.RecentSlots && slot < head-p.RecentSlots
+}
+
+// learnHead asks one endpoint for the head while no probe has reported it
+// (StartHealthChecks not called, or its first round still running), so slot
+// hints route from the first call. It returns 0 if that fails.
+func (p *HTTPPool) learnHead(ctx context.Context) uint64 {
+	ep := p.pick(false, nil)
+	if ep == nil {
+		return 0
+	}
+	hctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	slot, err := ep.client.GetSlot(hctx, "processed")
+	if err != nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.head = max(p.head, slot)
+	return p.head
+}
+
+// post tries one endpoint after another until a call succeeds or fails for
+// a reason another endpoint would not change (a bad request, a refused
+// batch). Pruned history and blocks not ready yet are not held against the
+// endpoint; pruned history moves the call to an archive endpoint.
+func (p *HTTPPool) post(ctx context.Context, c *HTTPClient, method string, credits float64, body []byte, decode func(*http.Response) error) error {
+	archive := p.wantsArchive(ctx)
+	tried := make(map[*httpEndpoint]bool, len(p.eps))
+	var lastErr error
+	for {
+		ep := p.pick(archive, tried)
+		if ep == nil {
+			return lastErr
+		}
+		tried[ep] = true
+		err := ep.client.post(ctx, method, credits, body, decode)
+		if err == nil {
+			p.succeeded(c, ep)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		lastErr = err
+		switch {
+		case IsHistoryUnavailable(err):
+			archive = true
+		case IsBlockNotAvailable(err):
+			// Not ready on this node yet; another one may have it.
+		case IsRetryable(err):
+			p.failed(c, ep, err)
+		default:
+			return err
+		}
+		if c.Hooks.OnFailover != nil {
+			c.Hooks.OnFailover(EndpointLabel(ep.URL), method)
+		}
+	}
+}
+
+// pick chooses an untried endpoint by weight: archive ones first when wanted,
+// then any that is not ejected. When every untried endpoint is ejected it
+// takes the one closest to reinstatement rather than failing the call.
+func (p *HTTPPool) pick(archive bool, tried map[*httpEndpoint]bool) *httpEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var live, arch []*httpEndpoint
+	var soonest *httpEndpoint
+	for _, ep := range p.eps {
+		if tried[ep] {
+			continue
+		}
+		if now.Before(ep.ejectedUntil) {
+			if soonest == nil || ep.ejectedUntil.Before(soonest.ejectedUntil) {
+				soonest = ep
+			}
+			continue
+		}
+		live = append(live, ep)
+		if ep.Archive {
+			arch = append(arch, ep)
+		}
+	}
+	if archive && len(arch) > 0 {
+		return weighted(arch)
+	}
+	if len(live) > 0 {
+		return weighted(live)
+	}
+	return soonest
+}
+
+func weighted(eps []*httpEndpoint) *httpEndpoint {
+	total := 0
+	for _, ep := range eps {
+		total += max(ep.Weight, 1)
+	}
+	n := rand.Intn(total)
+	for _, ep := range eps {
+		if n -= max(ep.Weight, 1); n < 0 {
+			return ep
+		}
+	}
+	return eps[len(eps)-1]
+}
+
+func (p *HTTPPool) succeeded(c *HTTPClient, ep *httpEndpoint) {
+	p.mu.Lock()
+	reinstated := !ep.ejectedUntil.IsZero()
+	ep.failures, ep.ejectedUntil = 0, time.Time{}
+	p.mu.Unlock()
+	if reinstated {
+		log.Printf("rpc: %s reinstated", EndpointLabel(ep.URL))
+		if c.Hooks.OnEndpointHealth != nil {
+			c.Hooks.OnEndpointHealth(EndpointLabel(ep.URL), true)
+		}
+	}
+}
+
+func (p *HTTPPool) failed(c *HTTPClient, ep *httpEndpoint, err error) {
+	p.mu.Lock()
+	ep.failures++
+	n := ep.failures
+	eject := n >= p.EjectAfter && time.Now().After(ep.ejectedUntil)
+	if eject {
+		ep.ejectedUntil = time.Now().Add(p.EjectFor)
+	}
+	p.mu.Unlock()
+	if eject {
+		log.Printf("rpc: ejecting %s for %s after %d failures: %v", EndpointLabel(ep.URL), p.EjectFor, n, err)
+		if c.Hooks.OnEndpointHealth != nil {
+			c.Hooks.OnEndpointHealth(EndpointLabel(ep.URL), false)
+		}
+	}
+}
+
+// StartHealthChecks probes every pool endpoint with getSlot each
+// ProbeInterval until ctx ends. Probes learn the chain head for slot routing,
+// reinstate ejected endpoints that answer and keep up, and count failures
+// against those that do not. It is a no-op for a single-endpoint client.
+func (c *HTTPClient) StartHealthChecks(ctx context.Context) {
+	p := c.pool
+	if p == nil || !p.probing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		t := time.NewTicker(p.ProbeInterval)
+		defer t.Stop()
+		for {
+			p.probeRound(ctx, c)
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+func (p *HTTPPool) probeRound(ctx context.Context, c *HTTPClient) {
+	slots := make([]uint64, len(p.eps))
+	errs := make([]error, len(p.eps))
+	var wg sync.WaitGroup
+	for i, ep := range p.eps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			slots[i], errs[i] = ep.client.GetSlot(pctx, "processed")
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	p.mu.Lock()
+	for _, s := range slots {
+		p.head = max(p.head, s)
+	}
+	head := p.head
+	p.mu.Unlock()
+	for i, ep := range p.eps {
+		switch {
+		case errs[i] != nil:
+			p.failed(c, ep, errs[i])
+		case head-slots[i] > p.MaxLag:
+			p.failed(c, ep, &RPCError{Code: CodeNodeUnhealthy, Message: "behind by " + strconv.FormatUint(head-slots[i], 10) + " slots"})
+		default:
+			p.succeeded(c, ep)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket shared by every request to one endpoint. Each
+// request costs its method's credits (1 unless configured), so heavy calls
+// like getBlock can be weighted the way providers bill them. A 429 pauses
+// the whole bucket until the provider's Retry-After.
+type Limiter struct {
+	Rate    float64            // credits per second; <= 0 disables the bucket
+	Burst   float64            // credits that can be spent at once
+	Credits map[string]float64 // per-method cost overrides
+
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func NewLimiter(rate, burst float64) *Limiter {
+	if burst <= 0 {
+		burst = max(rate, 1)
+	}
+	return &Limiter{Rate: rate, Burst: burst, tokens: burst, last: time.Now()}
+}
+
+// cost is what one call of method spends; nil-safe.
+func (l *Limiter) cost(method string) float64 {
+	if l == nil {
+		return 1
+	}
+	if c, ok := l.Credits[method]; ok {
+		return c
+	}
+	return 1
+}
+
+// Wait blocks until credits can be spent (or a 429 pause ends) and returns
+// how long it waited. Requests are served in arrival order: the bucket may go
+// into debt, and later callers wait for it to refill.
+func (l *Limiter) Wait(ctx context.Context, credits float64) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	var wait time.Duration
+	if l.Rate > 0 {
+		l.tokens = min(l.Burst, l.tokens+now.Sub(l.last).Seconds()*l.Rate)
+		l.last = now
+		l.tokens -= credits
+		if l.tokens < 0 {
+			wait = time.Duration(-l.tokens / l.Rate * float64(time.Second))
+		}
+	}
+	wait = max(wait, l.pausedUntil.Sub(now))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return 0, nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return wait, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		if l.Rate > 0 {
+			l.tokens += credits // not spent after all
+		}
+		l.mu.Unlock()
+		return time.Since(now), ctx.Err()
+	}
+}
+
+// PauseUntil holds every caller until t, e.g. after a 429 with Retry-After.
+func (l *Limiter) PauseUntil(t time.Time) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t.After(l.pausedUntil) {
+		l.pausedUntil = t
+	}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*Limiter{}
+)
+
+// LimiterFor returns the limiter shared by all clients of endpoint, created
+// on first use from RPC_RPS (credits/sec, 0 = unlimited), RPC_BURST and
+// RPC_METHOD_CREDITS ("getBlock=10,getTransaction=2").
+func LimiterFor(endpoint string) *Limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if l, ok := limiters[endpoint]; ok {
+		return l
+	}
+	rate, _ := strconv.ParseFloat(os.Getenv("RPC_RPS"), 64)
+	burst, _ := strconv.ParseFloat(os.Getenv("RPC_BURST"), 64)
+	l := NewLimiter(rate, burst)
+	l.Credits = ParseCredits(os.Getenv("RPC_METHOD_CREDITS"))
+	limiters[endpoint] = l
+	return l
+}
+
+// ParseCredits reads "method=credits,method=credits"; bad entries are skipped.
+func ParseCredits(s string) map[string]float64 {
+	out := map[string]float64{}
+	for _, part := range strings.Split(s, ",") {
+		method, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		if c, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && c >= 0 {
+			out[strings.TrimSpace(method)] = c
+		}
+	}
+	return out
+}
+
+// retryAfter reads a Retry-After header (seconds or an HTTP date); 0 if absent.
+func retryAfter(h http.Header) time.Duration {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// HTTPHooks let callers count HTTPClient limiter, throttling and cache events,
+// typically as metrics. Nil funcs are skipped.
+type HTTPHooks struct {
+	OnWait      func(endpoint, method string, d time.Duration) // time spent in the limiter before a request
+	OnThrottled func(endpoint, method string)                  // the provider answered 429
+	OnCache     func(method string, hit bool)                  // a cacheable call was looked up in Cache
+
+	// HTTPPool only.
+	OnFailover       func(endpoint, method string)  // a call failed on endpoint and moved to another
+	OnEndpointHealth func(endpoint string, ok bool) // an endpoint was ejected (false) or reinstated (true)
+}
+
+// DefaultHTTPHooks is copied into every client NewHTTPClient creates.
+var DefaultHTTPHooks HTTPHooks
@@ -3,26 +3,131 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 type WSClient struct {
-	URL        string
+	URL        string // first configured endpoint
+	Pool       *EndpointPool
 	dialer     *websocket.Dialer
 	MaxBackoff time.Duration
+
+	// BufferSize and Overflow shape each subscription's channel: how many
+	// notifications it holds and what happens when the consumer falls behind.
+	BufferSize int
+	Overflow   OverflowPolicy
+
+	// A ping goes out every PingInterval; a connection that delivers nothing
+	// (not even a pong) for ReadTimeout is dropped and redialed.
+	PingInterval time.Duration
+	ReadTimeout  time.Duration
+
+	Hooks WSHooks
+
+	errs chan error
+}
+
+// OverflowPolicy decides what a subscription does with a notification when
+// its channel is full.
+type OverflowPolicy int
+
+const (
+	OverflowDropNewest OverflowPolicy = iota // discard the incoming notification (default)
+	OverflowDropOldest                       // discard the oldest buffered one to make room
+	OverflowBlock                            // wait for the consumer; a stall past ReadTimeout drops the connection
+)
+
+// ParseOverflowPolicy accepts "drop-newest", "drop-oldest" or "block".
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "", "drop-newest":
+		return OverflowDropNewest, nil
+	case "drop-oldest":
+		return OverflowDropOldest, nil
+	case "block":
+		return OverflowBlock, nil
+	}
+	return OverflowDropNewest, fmt.Errorf("unknown overflow policy %q (drop-newest, drop-oldest, block)", s)
+}
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowBlock:
+		return "block"
+	default:
+		return "drop-newest"
+	}
+}
+
+// WSHooks let callers count WSClient events, typically as metrics. Nil
+// funcs are skipped; they are called from the read loop and must not block.
+type WSHooks struct {
+	OnDrop           func(method string)            // a notification was discarded by the overflow policy
+	OnSubscribeError func(method string, err error) // the node rejected a subscribe request
+	OnReconnect      func(url string, err error)    // a connection ended and will be redialed
+}
+
+// ErrDropped is wrapped by the errors Errors reports for discarded notifications.
+var ErrDropped = errors.New("notifications dropped")
+
+// SubscribeError is a subscribe request the node answered with an error.
+type SubscribeError struct {
+	Method  string
+	Code    int
+	Message string
+}
+
+func (e *SubscribeError) Error() string {
+	return fmt.Sprintf("%s rejected: %s (code %d)", e.Method, e.Message, e.Code)
 }
 
+// Errors reports subscribe failures (*SubscribeError) and dropped
+// notifications (wrapping ErrDropped, at most once a second per
+// subscription). Reports are discarded when nobody reads the channel.
+func (c *WSClient) Errors() <-chan error { return c.errs }
+
+func (c *WSClient) report(err error) {
+	select {
+	case c.errs <- err:
+	default:
+	}
+}
+
+// NewWSClient accepts a single URL or a failover list ("wss://a|0,wss://b|1"; see ParseWSEndpoints).
 func NewWSClient(url string) *WSClient {
+	eps := ParseWSEndpoints(url)
+	if len(eps) == 0 {
+		eps = []WSEndpoint{{URL: url}}
+	}
 	return &WSClient{
-		URL:        url,
+		URL:        eps[0].URL,
+		Pool:       NewEndpointPool(eps),
 		dialer:     &websocket.Dialer{HandshakeTimeout: 10 * time.Second},
 		MaxBackoff: 20 * time.Second,
+
+		BufferSize:   256,
+		PingInterval: 20 * time.Second,
+		ReadTimeout:  90 * time.Second,
+		errs:         make(chan error, 16),
 	}
 }
 
+// Start probes the failover list in the background until ctx ends, keeping
+// endpoint latency and slot lag current for failover and failback. Call it
+// once with a ctx that lives as long as the client, not a subscription's.
+// It is a no-op for a single endpoint.
+func (c *WSClient) Start(ctx context.Context) {
+	c.Pool.StartProbing(ctx)
+}
+
 // LogMsg is an envelope for logsSubscribe notifications.
 type LogMsg struct {
 	Params struct {
@@ -41,12 +146,198 @@ type LogMsg struct {
 }
 
 func (c *WSClient) SubscribeLogs(ctx context.Context, filter any) (<-chan LogMsg, error) {
-	out := make(chan LogMsg, 256)
+	return subscribe[LogMsg](ctx, c, "logsSubscribe", []any{filter, "confirmed"}), nil
+}
+
+// AccountSubscribeOpts configures accountSubscribe. Empty fields use the node's defaults
+// (base58 data, finalized commitment).
+type AccountSubscribeOpts struct {
+	Encoding   string `json:"encoding,omitempty"` // base58|base64|base64+zstd|jsonParsed
+	Commitment string `json:"commitment,omitempty"`
+}
+
+// AccountValue is the account state carried by account notifications.
+type AccountValue struct {
+	Lamports   uint64          `json:"lamports"`
+	Owner      string          `json:"owner"`
+	Executable bool            `json:"executable"`
+	RentEpoch  uint64          `json:"rentEpoch"`
+	Space      uint64          `json:"space"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// AccountMsg is an envelope for accountSubscribe notifications.
+type AccountMsg struct {
+	Params struct {
+		Result struct {
+			Context struct {
+				Slot uint64 `json:"slot"`
+			} `json:"context"`
+			Value AccountValue `json:"value"`
+		} `json:"result"`
+		Subscription int `json:"subscription"`
+	} `json:"params"`
+}
+
+// SubscribeAccount streams every change to one account.
+func (c *WSClient) SubscribeAccount(ctx context.Context, pubkey string, opts AccountSubscribeOpts) (<-chan AccountMsg, error) {
+	return subscribe[AccountMsg](ctx, c, "accountSubscribe", []any{pubkey, opts}), nil
+}
+
+// MemcmpFilter matches accounts whose data at Offset equals Bytes.
+type MemcmpFilter struct {
+	Offset   uint64 `json:"offset"`
+	Bytes    string `json:"bytes"`
+	Encoding string `json:"encoding,omitempty"` // base58 (default) | base64
+}
+
+// ProgramFilter is one entry of a programSubscribe/getProgramAccounts filter
+// list; set exactly one field. All filters in a list must match.
+type ProgramFilter struct {
+	Memcmp   *MemcmpFilter `json:"memcmp,omitempty"`
+	DataSize *uint64       `json:"dataSize,omitempty"`
+}
+
+// ProgramSubscribeOpts configures programSubscribe.
+type ProgramSubscribeOpts struct {
+	Encoding   string          `json:"encoding,omitempty"`
+	Commitment string          `json:"commitment,omitempty"`
+	Filters    []ProgramFilter `json:"filters,omitempty"`
+}
+
+// ProgramMsg is an envelope for programSubscribe notifications.
+type ProgramMsg struct {
+	Params struct {
+		Result struct {
+			Context struct {
+				Slot uint64 `json:"slot"`
+			} `json:"context"`
+			Value struct {
+				Pubkey  string       `json:"pubkey"`
+				Account AccountValue `json:"account"`
+			} `json:"value"`
+		} `json:"result"`
+		Subscription int `json:"subscription"`
+	} `json:"params"`
+}
+
+// SubscribeProgram streams changes to every account owned by programID that
+// matches opts.Filters (e.g. all token accounts of a mint: dataSize 165 and
+// memcmp at offset 0 with the mint).
+func (c *WSClient) SubscribeProgram(ctx context.Context, programID string, opts ProgramSubscribeOpts) (<-chan ProgramMsg, error) {
+	return subscribe[ProgramMsg](ctx, c, "programSubscribe", []any{programID, opts}), nil
+}
+
+// SlotMsg is an envelope for slotSubscribe notifications. Slot is the newest
+// processed slot; Root is the node's latest root at the time.
+type SlotMsg struct {
+	Params struct {
+		Result struct {
+			Slot   uint64 `json:"slot"`
+			Parent uint64 `json:"parent"`
+			Root   uint64 `json:"root"`
+		} `json:"result"`
+		Subscription int `json:"subscription"`
+	} `json:"params"`
+}
+
+// SubscribeSlots streams every slot the node processes.
+func (c *WSClient) SubscribeSlots(ctx context.Context) (<-chan SlotMsg, error) {
+	return subscribe[SlotMsg](ctx, c, "slotSubscribe", []any{}), nil
+}
+
+// RootMsg is an envelope for rootSubscribe notifications; the result is the new root slot.
+type RootMsg struct {
+	Params struct {
+		Result       uint64 `json:"result"`
+		Subscription int    `json:"subscription"`
+	} `json:"params"`
+}
+
+// SubscribeRoots streams each new root (finalized) slot.
+func (c *WSClient) SubscribeRoots(ctx context.Context) (<-chan RootMsg, error) {
+	return subscribe[RootMsg](ctx, c, "rootSubscribe", []any{}), nil
+}
+
+// SignatureMsg is an envelope for signatureSubscribe notifications. Value is
+// {"err": ...} once the signature reaches the subscribed commitment.
+type SignatureMsg struct {
+	Params struct {
+		Result struct {
+			Context struct {
+				Slot uint64 `json:"slot"`
+			} `json:"context"`
+			Value struct {
+				Err any `json:"err"`
+			} `json:"value"`
+		} `json:"result"`
+		Subscription int `json:"subscription"`
+	} `json:"params"`
+}
+
+// SubscribeSignature waits for sig to reach commitment. The node sends one
+// notification and then drops the subscription on its side.
+func (c *WSClient) SubscribeSignature(ctx context.Context, sig, commitment string) (<-chan SignatureMsg, error) {
+	return subscribe[SignatureMsg](ctx, c, "signatureSubscribe", []any{sig, map[string]any{"commitment": commitment}}), nil
+}
+
+// BlockMsg is an envelope for blockSubscribe notifications. Block is nil
+// when the node could not produce it (Err says why).
+type BlockMsg struct {
+	Params struct {
+		Result struct {
+			Context struct {
+				Slot uint64 `json:"slot"`
+			} `json:"context"`
+			Value struct {
+				Slot  uint64 `json:"slot"`
+				Block *Block `json:"block"`
+				Err   any    `json:"err"`
+			} `json:"value"`
+		} `json:"result"`
+		Subscription int `json:"subscription"`
+	} `json:"params"`
+}
+
+// SubscribeBlocks streams confirmed/finalized blocks with full transactions.
+// mentions narrows it to blocks touching one account or program ("" = all).
+// blockSubscribe is unstable and disabled on many providers; callers should
+// be ready to fall back to GetBlock polling.
+func (c *WSClient) SubscribeBlocks(ctx context.Context, mentions, commitment string) (<-chan BlockMsg, error) {
+	var filter any = "all"
+	if mentions != "" {
+		filter = map[string]any{"mentionsAccountOrProgram": mentions}
+	}
+	opts := map[string]any{
+		"commitment":                     commitment,
+		"encoding":                       "json",
+		"transactionDetails":             "full",
+		"showRewards":                    false,
+		"maxSupportedTransactionVersion": 0,
+	}
+	return subscribe[BlockMsg](ctx, c, "blockSubscribe", []any{filter, opts}), nil
+}
+
+// Standalone generic function (like rpcDo): runs the dial/subscribe/read loop
+// shared by the Subscribe* methods, reconnecting and resubscribing as needed,
+// and decodes each notification into T.
+func subscribe[T any](ctx context.Context, c *WSClient, method string, params []any) <-chan T {
+	out := make(chan T, max(c.BufferSize, 1))
+	drops := &dropReporter{c: c, method: method}
 
 	go func() {
 		defer close(out)
 
 		backoff := 500 * time.Millisecond
+		retry := func() bool {
+			backoff = minDuration(backoff*2, c.MaxBackoff)
+			select {
+			case <-time.After(backoff):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
 		for {
 			select {
 			case <-ctx.Done():
@@ -54,26 +345,24 @@ func (c *WSClient) SubscribeLogs(ctx context.Context, filter any) (<-chan LogMsg
 			default:
 			}
 
-			conn, _, err := c.dialer.Dial(c.URL, nil)
+			url := c.Pool.Pick()
+			conn, _, err := c.dialer.Dial(url, nil)
 			if err != nil {
 				log.Printf("ws: dial error: %v", err)
-				backoff = minDuration(backoff*2, c.MaxBackoff)
-				select {
-				case <-time.After(backoff):
-					continue
-				case <-ctx.Done():
+				c.Pool.ReportFailure(url)
+				if !retry() {
 					return
 				}
+				continue
 			}
-			backoff = 500 * time.Millisecond
-			log.Printf("ws: connected to %s", c.URL)
+			log.Printf("ws: connected to %s", url)
 
 			// Subscribe
 			subReq := map[string]any{
 				"jsonrpc": "2.0",
 				"id":      1,
-				"method":  "logsSubscribe",
-				"params":  []any{filter, "confirmed"},
+				"method":  method,
+				"params":  params,
 			}
 			if err := conn.WriteJSON(subReq); err != nil {
 				log.Printf("ws: write subscribe error: %v", err)
@@ -81,48 +370,178 @@ func (c *WSClient) SubscribeLogs(ctx context.Context, filter any) (<-chan LogMsg
 				continue
 			}
 
+			conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+			})
+
+			// Written by the reader before readDone closes.
 			readDone := make(chan struct{})
+			var readErr error
+			var subscribed bool
 			go func() {
 				defer close(readDone)
 				for {
 					_, data, err := conn.ReadMessage()
 					if err != nil {
+						readErr = err
 						log.Printf("ws: read error: %v", err)
 						return
 					}
-					if !json.Valid(data) {
-						continue
+					conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+					var f struct {
+						ID     *int            `json:"id"`
+						Error  *RPCError       `json:"error"`
+						Params json.RawMessage `json:"params"`
 					}
-					var maybe map[string]any
-					if err := json.Unmarshal(data, &maybe); err != nil {
+					if err := json.Unmarshal(data, &f); err != nil {
 						continue
 					}
-					// notifications carry "params"
-					if maybe["params"] != nil {
-						var msg LogMsg
-						if err := json.Unmarshal(data, &msg); err == nil {
-							select {
-							case out <- msg:
-							default:
-								// drop on backpressure; count later
-							}
+					if f.ID != nil && *f.ID == 1 {
+						if f.Error == nil {
+							subscribed = true
+							continue
+						}
+						readErr = &SubscribeError{Method: method, Code: f.Error.Code, Message: f.Error.Message}
+						log.Printf("ws: %v", readErr)
+						if c.Hooks.OnSubscribeError != nil {
+							c.Hooks.OnSubscribeError(method, readErr)
 						}
+						c.report(readErr)
+						return
+					}
+					// notifications carry "params"
+					if f.Params == nil {
+						continue
+					}
+					var msg T
+					if err := json.Unmarshal(data, &msg); err != nil {
+						continue
+					}
+					if !deliver(ctx, out, msg, c.Overflow, drops) {
+						return
 					}
 				}
 			}()
+			go c.keepalive(conn, readDone)
 
-			select {
-			case <-ctx.Done():
-				_ = conn.Close()
+			if !c.waitConn(ctx, conn, url, readDone) {
 				return
-			case <-readDone:
-				_ = conn.Close()
-				// loop and reconnect
+			}
+			if subscribed {
+				backoff = 500 * time.Millisecond
+			}
+			if c.Hooks.OnReconnect != nil {
+				c.Hooks.OnReconnect(url, readErr)
+			}
+			var se *SubscribeError
+			if errors.As(readErr, &se) && !retry() {
+				return // a rejected subscribe is retried with backoff, not in a tight loop
 			}
 		}
 	}()
 
-	return out, nil
+	return out
+}
+
+// deliver hands msg to the consumer according to policy. It returns false
+// only when ctx ends while blocked.
+func deliver[T any](ctx context.Context, out chan T, msg T, policy OverflowPolicy, drops *dropReporter) bool {
+	select {
+	case out <- msg:
+		return true
+	default:
+	}
+	switch policy {
+	case OverflowBlock:
+		select {
+		case out <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	case OverflowDropOldest:
+		select {
+		case <-out:
+			drops.add()
+		default:
+		}
+		select {
+		case out <- msg:
+		default:
+			drops.add() // the consumer refilled it meanwhile
+		}
+	default:
+		drops.add()
+	}
+	return true
+}
+
+// dropReporter counts discarded notifications for one subscription and
+// reports them through the hooks and, once a second at most, Errors.
+type dropReporter struct {
+	c      *WSClient
+	method string
+
+	mu       sync.Mutex
+	pending  int
+	reported time.Time
+}
+
+func (d *dropReporter) add() {
+	if d.c.Hooks.OnDrop != nil {
+		d.c.Hooks.OnDrop(d.method)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending++
+	if time.Since(d.reported) < time.Second {
+		return
+	}
+	d.c.report(fmt.Errorf("%s: %d %w (consumer too slow, policy %s)", d.method, d.pending, ErrDropped, d.c.Overflow))
+	d.pending, d.reported = 0, time.Now()
+}
+
+// keepalive pings until the read loop ends, so an idle but healthy
+// connection keeps extending its read deadline through pongs.
+func (c *WSClient) keepalive(conn *websocket.Conn, readDone <-chan struct{}) {
+	t := time.NewTicker(c.PingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-readDone:
+			return
+		case <-t.C:
+		}
+		if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
+			return
+		}
+	}
+}
+
+// waitConn blocks until the connection ends, ctx is cancelled (returns false)
+// or the pool wants a different endpoint (failover / failback).
+func (c *WSClient) waitConn(ctx context.Context, conn *websocket.Conn, url string, readDone <-chan struct{}) bool {
+	t := time.NewTicker(5 * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+			return false
+		case <-readDone:
+			_ = conn.Close()
+			c.Pool.ReportFailure(url)
+			return true // loop and reconnect
+		case <-t.C:
+			if next, ok := c.Pool.SwitchTarget(url); ok {
+				log.Printf("ws: failing over %s -> %s", url, next)
+				_ = conn.Close()
+				<-readDone
+				return true
+			}
+		}
+	}
 }
 
 func minDuration(a, b time.Duration) time.Duration {
@@ -0,0 +1,162 @@
+// Package rpccache holds the stores behind rpc.HTTPClient's Cache: a local
+// directory (Disk) or Redis (Redis). Both keep raw JSON results of finalized
+// getTransaction/getBlock calls, which never change once written.
+package rpccache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Disk keeps one gzipped file per entry under Dir, spread over 256
+// subdirectories. When the files pass MaxBytes the least recently used ones
+// (by mtime, which Get refreshes) are removed until 90% of MaxBytes is left.
+type Disk struct {
+	Dir      string
+	MaxBytes int64
+
+	mu   sync.Mutex
+	size int64
+}
+
+// OpenDisk uses (or creates) a cache in dir, counting what is already there
+// against maxBytes.
+func OpenDisk(dir string, maxBytes int64) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	d := &Disk{Dir: dir, MaxBytes: maxBytes}
+	for _, f := range d.files() {
+		d.size += f.size
+	}
+	return d, nil
+}
+
+func (d *Disk) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(d.Dir, name[:2], name[2:]+".json.gz")
+}
+
+func (d *Disk) Get(_ context.Context, key string) ([]byte, bool) {
+	p := d.path(key)
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, false
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(p, now, now)
+	return b, true
+}
+
+func (d *Disk) Put(_ context.Context, key string, value []byte) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write(value)
+	_ = zw.Close()
+	if int64(buf.Len()) > d.MaxBytes/10 {
+		return // one entry may not push out a tenth of the cache
+	}
+
+	p := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		log.Printf("rpccache: %v", err)
+		return
+	}
+	// Write to a temp file and rename, so a concurrent Get never sees half an entry.
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		log.Printf("rpccache: %v", err)
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	var old int64
+	if fi, serr := os.Stat(p); serr == nil {
+		old = fi.Size()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		log.Printf("rpccache: %v", err)
+		return
+	}
+
+	d.mu.Lock()
+	d.size += int64(buf.Len()) - old
+	over := d.size > d.MaxBytes
+	d.mu.Unlock()
+	if over {
+		d.evict()
+	}
+}
+
+type diskFile struct {
+	path  string
+	size  int64
+	mtime time.Time
+}
+
+func (d *Disk) files() []diskFile {
+	var out []diskFile
+	_ = filepath.WalkDir(d.Dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() || filepath.Ext(p) != ".gz" {
+			return nil
+		}
+		if fi, err := e.Info(); err == nil {
+			out = append(out, diskFile{p, fi.Size(), fi.ModTime()})
+		}
+		return nil
+	})
+	return out
+}
+
+// evict removes the least recently used entries until 90% of MaxBytes is
+// left, and recounts the size from what is on disk.
+func (d *Disk) evict() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	files := d.files()
+	sort.Slice(files, func(i, j int) bool { return files[i].mtime.Before(files[j].mtime) })
+	var size int64
+	for _, f := range files {
+		size += f.size
+	}
+	target := d.MaxBytes / 10 * 9
+	removed := 0
+	for _, f := range files {
+		if size <= target {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			size -= f.size
+			removed++
+		}
+	}
+	d.size = size
+	log.Printf("rpccache: evicted %d entries, %d bytes left", removed, size)
+}
@@ -0,0 +1,52 @@
+package rpccache
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+)
+
+// FromEnv returns the cache RPC_CACHE selects, or nil when it is unset:
+//
+//	disk   RPC_CACHE_DIR (default /data/rpc-cache), RPC_CACHE_MAX_MB (1024)
+//	redis  REDIS_URL, RPC_CACHE_TTL_HOURS (168), RPC_CACHE_MAX_ENTRY_KB (512)
+func FromEnv() (rpc.Cache, error) {
+	switch mode := os.Getenv("RPC_CACHE"); mode {
+	case "", "off":
+		return nil, nil
+	case "disk":
+		dir := getenv("RPC_CACHE_DIR", "/data/rpc-cache")
+		d, err := OpenDisk(dir, int64(envInt("RPC_CACHE_MAX_MB", 1024))<<20)
+		if err != nil {
+			return nil, fmt.Errorf("rpccache: %w", err)
+		}
+		return d, nil
+	case "redis":
+		opt, err := redis.ParseURL(getenv("REDIS_URL", "redis://redis:6379/0"))
+		if err != nil {
+			return nil, fmt.Errorf("rpccache: REDIS_URL: %w", err)
+		}
+		ttl := time.Duration(envInt("RPC_CACHE_TTL_HOURS", 168)) * time.Hour
+		return NewRedis(redis.NewClient(opt), ttl, envInt("RPC_CACHE_MAX_ENTRY_KB", 512)<<10), nil
+	default:
+		return nil, fmt.Errorf("rpccache: unknown RPC_CACHE %q (disk|redis)", mode)
+	}
+}
+
+func getenv(k, def string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return def
+}
+
+func envInt(k string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(k)); err == nil {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,43 @@
+package rpccache
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keeps entries as plain string keys under Prefix. Entries expire
+// after TTL and ones larger than MaxEntryBytes are not stored; the total is
+// bounded by the TTL and the server's maxmemory policy.
+type Redis struct {
+	RDB           redis.UniversalClient
+	Prefix        string
+	TTL           time.Duration
+	MaxEntryBytes int
+}
+
+func NewRedis(rdb redis.UniversalClient, ttl time.Duration, maxEntryBytes int) *Redis {
+	return &Redis{RDB: rdb, Prefix: "sol:rpccache:", TTL: ttl, MaxEntryBytes: maxEntryBytes}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool) {
+	b, err := r.RDB.Get(ctx, r.Prefix+key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("rpccache: %v", err)
+		}
+		return nil, false
+	}
+	return b, true
+}
+
+func (r *Redis) Put(ctx context.Context, key string, value []byte) {
+	if r.MaxEntryBytes > 0 && len(value) > r.MaxEntryBytes {
+		return
+	}
+	if err := r.RDB.Set(ctx, r.Prefix+key, value, r.TTL).Err(); err != nil {
+		log.Printf("rpccache: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+)
+
+// accountsStream carries account_update events; the worker persists them to
+// accounts (latest) and account_history (pubkey, slot).
+const accountsStream = "sol:accounts"
+
+// maxAccountData caps the raw data copied into the stream; larger accounts
+// are published without it.
+const maxAccountData = 64 << 10
+
+var (
+	accountUpdates = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sentinel_account_updates_total",
+		Help: "Account notifications received (pre-dedupe).",
+	})
+	accountsPublished = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sentinel_account_updates_published_total",
+		Help: "Account updates published to the sol:accounts stream.",
+	})
+)
+
+type accountEvent struct {
+	Pubkey       string
+	Slot         uint64
+	Value        rpc.AccountValue
+	Program      string // owning program for programSubscribe updates; empty otherwise
+	Subscription string
+	Provider     string
+}
+
+// publishAccount appends ev to sol:accounts, or spills it while Redis is down.
+func (p *publisher) publishAccount(ctx context.Context, ev accountEvent) bool {
+	ctx = context.WithoutCancel(ctx) // a started publish completes during shutdown
+	e := spillEntry{Account: &ev}
+	if p.standby.hold(e) {
+		return false
+	}
+	if p.spilling() {
+		return p.park(e)
+	}
+	ok, err := p.writeAccount(ctx, ev, &e.Claimed)
+	if err != nil {
+		return p.park(e)
+	}
+	return ok
+}
+
+// writeAccount dedupes on pubkey+slot and appends to sol:accounts.
+func (p *publisher) writeAccount(ctx context.Context, ev accountEvent, claimed *bool) (bool, error) {
+	ok, err := p.claim(ctx, p.dedupeKey("acct:"+ev.Pubkey+":"+itoa(ev.Slot)), ev.Provider, claimed)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		deduped.Inc()
+		return false, nil
+	}
+
+	data := ""
+	if len(ev.Value.Data) <= maxAccountData {
+		data = string(ev.Value.Data)
+	}
+	fields := map[string]any{
+		"kind":         "account_update",
+		"pubkey":       ev.Pubkey,
+		"slot":         ev.Slot,
+		"owner":        ev.Value.Owner,
+		"lamports":     ev.Value.Lamports,
+		"executable":   ev.Value.Executable,
+		"rent_epoch":   ev.Value.RentEpoch,
+		"space":        ev.Value.Space,
+		"data":         data,
+		"program":      ev.Program,
+		"subscription": ev.Subscription,
+		"provider":     ev.Provider,
+		"ts":           time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if _, err := p.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: accountsStream,
+		Values: fields,
+		MaxLen: 100000,
+	}).Result(); err != nil {
+		log.Printf("redis xadd err: %v", err)
+		return false, err
+	}
+	accountsPublished.Inc()
+	log.Printf("published account=%s slot=%d lamports=%d", ev.Pubkey, ev.Slot, ev.Value.Lamports)
+	return true, nil
+}
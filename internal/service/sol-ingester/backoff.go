@@ -0,0 +1,101 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	wsBackoff = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sentinel_ws_backoff_seconds",
+		Help: "Wait before the next reconnect attempt (0 while connected).",
+	}, []string{"conn"})
+	wsBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sentinel_ws_breaker_state",
+		Help: "Reconnect circuit breaker: 0 closed, 1 half-open, 2 open.",
+	}, []string{"conn"})
+	wsBreakerTrips = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_ws_breaker_trips_total",
+		Help: "Times the reconnect circuit breaker opened.",
+	}, []string{"conn"})
+)
+
+const (
+	breakerClosed = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+// healthyConn is how long a connection must last for its drop to count as a
+// fresh failure rather than part of a losing streak.
+const healthyConn = time.Minute
+
+// retryPolicy paces reconnects for one connection loop: exponential backoff
+// with jitter, and a circuit breaker that stops dialing for cooldown after
+// threshold failures in a row. The first attempt after the cooldown is a
+// half-open probe; if it fails the breaker opens again.
+type retryPolicy struct {
+	conn      string // metric label
+	base      time.Duration
+	max       time.Duration
+	threshold int
+	cooldown  time.Duration
+
+	failures int
+	state    int
+}
+
+func newRetryPolicy(conn string) *retryPolicy {
+	return &retryPolicy{
+		conn:      conn,
+		base:      time.Duration(envInt("WS_BACKOFF_BASE_MS", 500)) * time.Millisecond,
+		max:       time.Duration(envInt("WS_BACKOFF_MAX_MS", 30000)) * time.Millisecond,
+		threshold: envInt("WS_BREAKER_FAILURES", 8),
+		cooldown:  time.Duration(envInt("WS_BREAKER_COOLDOWN_SEC", 60)) * time.Second,
+	}
+}
+
+// attempt is called before each dial.
+func (r *retryPolicy) attempt() {
+	if r.state == breakerOpen {
+		r.setState(breakerHalfOpen)
+	}
+	wsBackoff.WithLabelValues(r.conn).Set(0)
+}
+
+// reset forgets past failures after a healthy connection.
+func (r *retryPolicy) reset() {
+	r.failures = 0
+	r.setState(breakerClosed)
+}
+
+// failed records a failure and returns how long to wait before the next attempt.
+func (r *retryPolicy) failed() time.Duration {
+	r.failures++
+	d := r.base << min(r.failures-1, 20)
+	if d <= 0 || d > r.max {
+		d = r.max
+	}
+	// Equal jitter: keep half the delay, randomise the rest, so replicas that
+	// lost the same endpoint do not reconnect in lockstep.
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+
+	if r.state == breakerHalfOpen || (r.state == breakerClosed && r.failures >= r.threshold) {
+		wsBreakerTrips.WithLabelValues(r.conn).Inc()
+		log.Printf("[%s] %d failures in a row; circuit open for %s", r.conn, r.failures, r.cooldown)
+		r.setState(breakerOpen)
+	}
+	if r.state == breakerOpen {
+		d = max(d, r.cooldown)
+	}
+	wsBackoff.WithLabelValues(r.conn).Set(d.Seconds())
+	return d
+}
+
+func (r *retryPolicy) setState(s int) {
+	r.state = s
+	wsBreakerState.WithLabelValues(r.conn).Set(float64(s))
+}
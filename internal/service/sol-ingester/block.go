@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+	"github.com/rileyafox/solana-sentinel/internal/subs"
+)
+
+var (
+	blocksProcessed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sentinel_blocks_processed_total",
+		Help: "Blocks scanned by the block ingestion source.",
+	})
+	blocksSkipped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sentinel_blocks_skipped_total",
+		Help: "Slots getBlock reported as skipped or missing.",
+	})
+	blockTxMatched = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sentinel_block_tx_matched_total",
+		Help: "Block transactions that mention a watched address.",
+	})
+	blockLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sentinel_block_cursor_lag_slots",
+		Help: "Tip slot minus the last slot the block source processed.",
+	})
+)
+
+// blockSource ingests whole blocks instead of log notifications, so every
+// transaction mentioning a watched address is seen, with its block time and
+// index. Blocks come from blockSubscribe when enabled; getBlocks/getBlock
+// polling fills anything the subscription misses, or does all the work.
+type blockSource struct {
+	http       *rpc.HTTPClient
+	ws         *rpc.WSClient // nil = poll only
+	pub        *publisher
+	desired    *desiredSet
+	commitment string // confirmed or finalized
+	interval   time.Duration
+	maxRange   uint64 // slots per getBlocks call
+	cursor     uint64 // last slot processed
+	retry      uint64 // subscribed slot whose catch-up failed; the next tick retries up to it
+
+	nullSlot  uint64 // slot getBlock last answered with a null result
+	nullTries int    // consecutive null results for nullSlot
+}
+
+// maxNullTries is how often a listed slot may come back null before it is
+// counted as skipped: some providers answer null instead of -32007/-32009
+// for blocks they do not have (yet) or have cleaned up.
+const maxNullTries = 5
+
+func (b *blockSource) run(ctx context.Context) {
+	for b.cursor == 0 {
+		tip, err := b.http.GetSlot(ctx, b.commitment)
+		if err == nil {
+			b.cursor = tip
+			break
+		}
+		log.Printf("[block] getSlot: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.interval):
+		}
+	}
+	log.Printf("[block] starting after slot %d (commitment=%s, subscribe=%v)", b.cursor, b.commitment, b.ws != nil)
+
+	var blocks <-chan rpc.BlockMsg
+	if b.ws != nil {
+		blocks, _ = b.ws.SubscribeBlocks(ctx, "", b.commitment)
+	}
+	t := time.NewTicker(b.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-blocks:
+			if !ok {
+				blocks = nil
+				continue
+			}
+			v := m.Params.Result.Value
+			if v.Block == nil || v.Slot <= b.cursor {
+				continue
+			}
+			if v.Slot > b.cursor+1 {
+				if err := b.catchUp(ctx, v.Slot-1); err != nil {
+					log.Printf("[block] catch up to %d: %v", v.Slot-1, err)
+					// v.Block is dropped with the rest; the tick fetches it again.
+					b.retry = max(b.retry, v.Slot)
+					continue
+				}
+			}
+			b.process(ctx, v.Slot, v.Block)
+			b.cursor = v.Slot
+		case <-t.C:
+			tip, err := b.http.GetSlot(ctx, b.commitment)
+			if err != nil {
+				log.Printf("[block] getSlot: %v", err)
+				continue
+			}
+			// With a live subscription, only poll for what it has clearly missed
+			// or failed to catch up on.
+			if blocks != nil && tip <= b.cursor+b.maxRange {
+				if b.retry > b.cursor {
+					if err := b.catchUp(ctx, b.retry); err != nil {
+						log.Printf("[block] catch up to %d: %v", b.retry, err)
+					}
+				}
+				blockLag.Set(float64(tip - min(tip, b.cursor)))
+				continue
+			}
+			if err := b.catchUp(ctx, tip); err != nil {
+				log.Printf("[block] catch up to %d: %v", tip, err)
+			}
+			blockLag.Set(float64(tip - min(tip, b.cursor)))
+		}
+	}
+}
+
+// catchUp processes every block after the cursor up to and including to.
+// It stops at the first block that cannot be fetched, leaving the cursor just
+// before it so the next call retries.
+func (b *blockSource) catchUp(ctx context.Context, to uint64) error {
+	for b.cursor < to {
+		end := min(to, b.cursor+b.maxRange)
+		slots, err := b.http.GetBlocks(ctx, b.cursor+1, end, b.commitment)
+		if err != nil {
+			return err
+		}
+		for _, slot := range slots {
+			blk, err := b.http.GetBlock(ctx, slot, b.commitment)
+			if err != nil {
+				if rpc.IsSlotSkipped(err) {
+					blocksSkipped.Inc()
+					b.cursor = slot
+					continue
+				}
+				return err
+			}
+			if blk == nil {
+				if b.nullSlot != slot {
+					b.nullSlot, b.nullTries = slot, 0
+				}
+				if b.nullTries++; b.nullTries < maxNullTries {
+					return fmt.Errorf("block %d: null result", slot)
+				}
+				log.Printf("[block] slot %d: null result %d times; skipping", slot, b.nullTries)
+				blocksSkipped.Inc()
+				b.cursor = slot
+				continue
+			}
+			b.process(ctx, slot, blk)
+			b.cursor = slot
+		}
+		b.cursor = end
+	}
+	return nil
+}
+
+// process publishes every transaction in blk that mentions a watched address.
+func (b *blockSource) process(ctx context.Context, slot uint64, blk *rpc.Block) {
+	blocksProcessed.Inc()
+	cur, _ := b.desired.get()
+	addrs, all := watchedAddresses(cur)
+	for i := range blk.Transactions {
+		tx := &blk.Transactions[i]
+		if !all && !mentionsAny(tx, addrs) {
+			continue
+		}
+		blockTxMatched.Inc()
+		ev := logEvent{
+			Signature:  tx.Signature(),
+			Slot:       slot,
+			Provider:   "block",
+			Commitment: b.commitment,
+			BlockTime:  blk.BlockTime,
+			TxIndex:    &i,
+		}
+		if tx.Meta != nil {
+			ev.Err, ev.Logs = tx.Meta.Err, tx.Meta.LogMessages
+		}
+		b.pub.publish(ctx, ev)
+	}
+}
+
+// watchedAddresses collects the logs subscriptions' addresses; all is true
+// when the watch list includes "all".
+func watchedAddresses(cur []subs.Subscription) (addrs []string, all bool) {
+	for _, s := range cur {
+		if s.Kind != subs.KindLogs {
+			continue
+		}
+		if s.Address == "all" {
+			return nil, true
+		}
+		addrs = append(addrs, s.Address)
+	}
+	return addrs, false
+}
+
+func mentionsAny(tx *rpc.BlockTx, addrs []string) bool {
+	for _, a := range addrs {
+		if tx.Mentions(a) {
+			return true
+		}
+	}
+	return false
+}
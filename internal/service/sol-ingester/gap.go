@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+	"github.com/rileyafox/solana-sentinel/internal/subs"
+)
+
+var (
+	gapSlots = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "sentinel_gap_slots",
+		Help:    "Slots between the last notification before a reconnect and the newest signature found by catch-up.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+	})
+	gapFound = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sentinel_gap_signatures_total",
+		Help: "Signatures found by post-reconnect catch-up (pre-dedupe).",
+	})
+	gapRecovered = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sentinel_gap_recovered_total",
+		Help: "Signatures published by catch-up that the WebSocket never delivered.",
+	})
+	gapTruncated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sentinel_gap_truncated_total",
+		Help: "Catch-ups that stopped at GAP_MAX_SIGNATURES before reaching the last seen signature.",
+	})
+)
+
+type gapMark struct {
+	sig  string
+	slot uint64
+}
+
+// gapTracker remembers the newest notification per watched address. When a
+// subscription is re-established after a reconnect, it pages
+// getSignaturesForAddress back to that mark and publishes what was missed.
+type gapTracker struct {
+	http    *rpc.HTTPClient
+	pub     *publisher
+	maxSigs int
+
+	mu      sync.Mutex
+	marks   map[string]gapMark // by address
+	running map[string]bool
+}
+
+func newGapTracker(http *rpc.HTTPClient, pub *publisher, maxSigs int) *gapTracker {
+	return &gapTracker{
+		http:    http,
+		pub:     pub,
+		maxSigs: maxSigs,
+		marks:   map[string]gapMark{},
+		running: map[string]bool{},
+	}
+}
+
+func (g *gapTracker) seen(sub subs.Subscription, sig string, slot uint64) {
+	if g == nil || sub.Address == "all" || sig == "" {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if m, ok := g.marks[sub.Address]; !ok || slot >= m.slot {
+		g.marks[sub.Address] = gapMark{sig: sig, slot: slot}
+	}
+}
+
+// resubscribed starts a catch-up for sub if we saw it before; the first
+// subscription of an address has nothing to catch up.
+func (g *gapTracker) resubscribed(ctx context.Context, sub subs.Subscription) {
+	if g == nil || sub.Kind != subs.KindLogs || sub.Address == "all" {
+		return
+	}
+	g.mu.Lock()
+	mark, ok := g.marks[sub.Address]
+	if !ok || g.running[sub.Address] {
+		g.mu.Unlock()
+		return
+	}
+	g.running[sub.Address] = true
+	g.mu.Unlock()
+
+	go func() {
+		defer func() {
+			g.mu.Lock()
+			delete(g.running, sub.Address)
+			g.mu.Unlock()
+		}()
+		g.catchUp(ctx, sub, mark)
+	}()
+}
+
+func (g *gapTracker) catchUp(ctx context.Context, sub subs.Subscription, mark gapMark) {
+	const page = 1000
+	var found []rpc.SignatureInfo
+	truncated := false
+	before := ""
+	for {
+		sigs, err := g.http.GetSignaturesForAddressWithOpts(ctx, sub.Address, rpc.SignaturesOpts{
+			Limit:  page,
+			Before: before,
+			Until:  mark.sig,
+		})
+		if err != nil {
+			log.Printf("gap %s: getSignaturesForAddress: %v", sub.Address, err)
+			break
+		}
+		reached := len(sigs) < page
+		for _, si := range sigs {
+			if si.Slot < mark.slot {
+				// the mark itself may have been dropped by a fork; the slot bounds us
+				reached = true
+				break
+			}
+			found = append(found, si)
+		}
+		if reached {
+			break
+		}
+		if len(found) >= g.maxSigs {
+			truncated = true
+			break
+		}
+		before = sigs[len(sigs)-1].Signature
+	}
+	if len(found) > g.maxSigs {
+		found, truncated = found[:g.maxSigs], true
+	}
+	if truncated {
+		gapTruncated.Inc()
+	}
+	if len(found) == 0 {
+		return
+	}
+
+	gapFound.Add(float64(len(found)))
+	gapSlots.Observe(float64(found[0].Slot - mark.slot))
+
+	// Oldest first, so sol:logs stays roughly in slot order.
+	recovered := 0
+	for i := len(found) - 1; i >= 0; i-- {
+		si := found[i]
+		if g.pub.seen(ctx, si.Signature, si.Slot) {
+			continue
+		}
+		ev := logEvent{
+			Signature:  si.Signature,
+			Slot:       si.Slot,
+			Err:        si.Err,
+			Logs:       fetchLogs(ctx, g.http, si.Signature),
+			Provider:   "backfill",
+			Commitment: si.ConfirmationStatus,
+		}
+		if g.pub.publish(ctx, ev) {
+			recovered++
+			gapRecovered.Inc()
+		}
+	}
+	log.Printf("gap %s: found=%d recovered=%d slots=%d..%d truncated=%v",
+		sub.Address, len(found), recovered, mark.slot, found[0].Slot, truncated)
+}
+
+// fetchLogs returns a transaction's log messages (nil if it cannot be fetched).
+func fetchLogs(ctx context.Context, http *rpc.HTTPClient, sig string) []string {
+	tx, err := http.GetTransaction(ctx, sig)
+	if err != nil || tx == nil {
+		if err != nil {
+			log.Printf("getTransaction %s: %v", sig, err)
+		}
+		return nil
+	}
+	if tx.Meta == nil {
+		return nil
+	}
+	return tx.Meta.LogMessages
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+)
+
+var (
+	providerDelivered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_provider_delivered_total",
+		Help: "Log notifications delivered per provider in hedged mode (pre-dedupe).",
+	}, []string{"provider"})
+	providerFirst = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_provider_first_total",
+		Help: "Signatures a provider delivered before any other provider.",
+	}, []string{"provider"})
+	providerMissed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_provider_missed_total",
+		Help: "Signatures another provider delivered that this provider never did within the merge window.",
+	}, []string{"provider"})
+)
+
+// merger tracks, per signature, which hedged providers delivered it. Once a
+// signature is older than the window, every provider that did not deliver it
+// is charged a miss. Publishing itself is deduped in Redis; this is only the
+// provider comparison.
+type merger struct {
+	providers []string
+	window    time.Duration
+
+	mu   sync.Mutex
+	sigs map[string]*mergeEntry
+}
+
+type mergeEntry struct {
+	first string
+	seen  map[string]struct{}
+	at    time.Time
+}
+
+func newMerger(eps []rpc.WSEndpoint, window time.Duration) *merger {
+	m := &merger{window: window, sigs: map[string]*mergeEntry{}}
+	for _, ep := range eps {
+		label := rpc.EndpointLabel(ep.URL)
+		m.providers = append(m.providers, label)
+		providerDelivered.WithLabelValues(label).Add(0)
+		providerFirst.WithLabelValues(label).Add(0)
+		providerMissed.WithLabelValues(label).Add(0)
+	}
+	return m
+}
+
+func (m *merger) observe(provider, sig string) {
+	providerDelivered.WithLabelValues(provider).Inc()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.sigs[sig]
+	if !ok {
+		e = &mergeEntry{first: provider, seen: map[string]struct{}{}, at: time.Now()}
+		m.sigs[sig] = e
+		providerFirst.WithLabelValues(provider).Inc()
+	}
+	e.seen[provider] = struct{}{}
+}
+
+// run settles expired signatures until ctx is done.
+func (m *merger) run(ctx context.Context) {
+	t := time.NewTicker(m.window / 4)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			m.sweep(now)
+		}
+	}
+}
+
+func (m *merger) sweep(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for sig, e := range m.sigs {
+		if now.Sub(e.at) < m.window {
+			continue
+		}
+		for _, p := range m.providers {
+			if _, ok := e.seen[p]; !ok {
+				providerMissed.WithLabelValues(p).Inc()
+			}
+		}
+		delete(m.sigs, sig)
+	}
+}
@@ -7,14 +7,24 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
+	"github.com/rileyafox/solana-sentinel/internal/metrics"
+	"github.com/rileyafox/solana-sentinel/internal/record"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+	"github.com/rileyafox/solana-sentinel/internal/rpccache"
+	"github.com/rileyafox/solana-sentinel/internal/shard"
+	"github.com/rileyafox/solana-sentinel/internal/spill"
+	"github.com/rileyafox/solana-sentinel/internal/subs"
 )
 
 type wsReq struct {
@@ -58,8 +68,35 @@ var (
 		Name: "sentinel_ws_reconnects_total",
 		Help: "WebSocket reconnects.",
 	})
+	subEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_subscription_events_total",
+		Help: "Notifications received per configured subscription.",
+	}, []string{"subscription"})
+	subsActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sentinel_subscriptions_active",
+		Help: "Subscriptions acknowledged per connected endpoint.",
+	}, []string{"endpoint"})
+	endpointScore = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sentinel_ws_endpoint_score",
+		Help: "Health score (0-100) per WebSocket endpoint.",
+	}, []string{"endpoint"})
+	endpointActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sentinel_ws_endpoint_active",
+		Help: "1 for the WebSocket endpoint currently in use.",
+	}, []string{"endpoint"})
+	endpointLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sentinel_ws_endpoint_slot_lag",
+		Help: "Slots behind the freshest endpoint, from the last probe.",
+	}, []string{"endpoint"})
+	failovers = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sentinel_ws_failovers_total",
+		Help: "Connections dropped to move to a better endpoint.",
+	})
 )
 
+// live is the set of connected sessions, reported on /subscriptions.
+var live sessionSet
+
 func mustEnv(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -86,35 +123,291 @@ func mustRedisClient(raw string) *redis.Client {
 	return redis.NewClient(opt)
 }
 
+// newHTTPClient routes over SOLANA_HTTP_URLS ("https://a|3,https://b|1|archive")
+// when set, otherwise uses SOLANA_HTTP_URL alone. RPC_CACHE adds a response
+// cache for finalized blocks and transactions.
+func newHTTPClient(ctx context.Context) *rpc.HTTPClient {
+	urls := mustEnv("SOLANA_HTTP_URLS", mustEnv("SOLANA_HTTP_URL", "https://api.mainnet-beta.solana.com"))
+	c := rpc.NewHTTPPool(rpc.ParseHTTPEndpoints(urls))
+	cache, err := rpccache.FromEnv()
+	if err != nil {
+		log.Fatalf("rpc cache: %v", err)
+	}
+	c.Cache = cache
+	c.StartHealthChecks(ctx)
+	return c
+}
+
 func main() {
-	prometheus.MustRegister(ingested, deduped, published, reconnects)
+	prometheus.MustRegister(ingested, deduped, published, reconnects, subEvents, subsActive,
+		endpointScore, endpointActive, endpointLag, failovers,
+		providerDelivered, providerFirst, providerMissed,
+		gapSlots, gapFound, gapRecovered, gapTruncated,
+		accountUpdates, accountsPublished,
+		blocksProcessed, blocksSkipped, blockTxMatched, blockLag,
+		pollRequests, pollInterval, pollCooldown,
+		spillBytes, spillEvents,
+		shardMembers, shardOwned, standbyActive, standbyHeld,
+		wsBackoff, wsBreakerState, wsBreakerTrips,
+		metrics.RPCLimiterWait, metrics.RPCThrottled, metrics.RPCFailovers, metrics.RPCEndpointHealthy, metrics.RPCCacheRequests)
+	rpc.DefaultHTTPHooks = metrics.HTTPHooks()
+
+	// SIGINT/SIGTERM cancel ctx: sources stop, sessions unsubscribe and
+	// close, and the deferred closes below flush the recorder and the spill
+	// queue. A watchdog exits anyway once SHUTDOWN_TIMEOUT_SEC has passed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	go func() {
-		http.Handle("/metrics", promhttp.Handler())
-		addr := mustEnv("PROM_ADDR", ":9102")
-		log.Printf("Prometheus at %s/metrics", addr)
-		_ = http.ListenAndServe(addr, nil)
+		<-ctx.Done()
+		grace := time.Duration(envInt("SHUTDOWN_TIMEOUT_SEC", 15)) * time.Second
+		log.Printf("shutting down (deadline %s)", grace)
+		time.Sleep(grace)
+		log.Printf("shutdown deadline exceeded; exiting")
+		os.Exit(1)
 	}()
 
-	ctx := context.Background()
 	rdb := mustRedisClient(os.Getenv("REDIS_URL"))
 	defer rdb.Close()
 
-	wsURL := mustEnv("SOLANA_WS_URL", "wss://api.mainnet-beta.solana.com")
+	// SOLANA_WS_URLS takes a failover list ("wss://a|0,wss://b|1"); SOLANA_WS_URL still works alone.
+	wsURLs := mustEnv("SOLANA_WS_URLS", mustEnv("SOLANA_WS_URL", "wss://api.mainnet-beta.solana.com"))
+	pool := rpc.NewEndpointPool(rpc.ParseWSEndpoints(wsURLs))
+	pool.ProbeInterval = time.Duration(envInt("WS_PROBE_INTERVAL_SEC", 15)) * time.Second
+	pool.StartProbing(ctx)
+	go exportEndpointScores(ctx, pool)
 	commitment := mustEnv("SOLANA_COMMITMENT", "confirmed")
 	programs := splitCSV(os.Getenv("SUBSCRIBE_PROGRAMS"))
 	accounts := splitCSV(os.Getenv("SUBSCRIBE_ACCOUNTS"))
+	watched := splitCSV(os.Getenv("WATCH_ACCOUNTS")) // accountSubscribe + history
 	dedupeTTL := time.Duration(envInt("REDIS_DEDUPE_TTL_SEC", 86400)) * time.Second
 
-	for {
-		if err := runOnce(ctx, rdb, wsURL, commitment, programs, accounts, dedupeTTL); err != nil {
-			log.Printf("stream error: %v", err)
-			reconnects.Inc()
-			time.Sleep(backoff(err))
+	// The watch list lives in Redis (managed via the admin API); env vars only
+	// seed it on first start and serve as the fallback until Redis answers.
+	seed := subs.FromEnv(programs, accounts, watched)
+	desired := newDesiredSet(seed)
+	store := subs.NewStore(rdb)
+	if err := store.Seed(ctx, seed); err != nil {
+		log.Printf("seed subscriptions: %v", err)
+	}
+	go store.Watch(ctx, time.Duration(envInt("SUBS_POLL_SEC", 30))*time.Second, desired.set)
+
+	// SPILL_DIR parks events on disk while Redis is unreachable and replays
+	// them in order once it is back; without it they are dropped.
+	var bg sync.WaitGroup
+	pub := &publisher{rdb: rdb, dedupeTTL: dedupeTTL}
+	if dir := os.Getenv("SPILL_DIR"); dir != "" {
+		q, err := spill.Open(dir, int64(envInt("SPILL_MAX_MB", 1024))<<20)
+		if err != nil {
+			log.Fatalf("spill: %v", err)
+		}
+		defer q.Close()
+		if n := q.Pending(); n > 0 {
+			log.Printf("spill: %d bytes left from the last run", n)
+		}
+		pub.spill = q
+		bg.Add(1)
+		go func() {
+			defer bg.Done()
+			pub.drainSpill(ctx)
+		}()
+	}
+	// Runs before the closes above: background loops that still write to
+	// Redis or the spill queue finish first.
+	defer bg.Wait()
+
+	// Replicas coordinate through Redis. SHARD_MODE=hash splits the watch
+	// list between them by consistent hashing on the subscription ID;
+	// SHARD_MODE=standby has every replica subscribe but only the lease
+	// holder publish. owned is what this replica subscribes to and polls:
+	// every source below reads it, never desired directly.
+	owned := desired
+	shardTTL := time.Duration(envInt("SHARD_TTL_SEC", 15)) * time.Second
+	switch mode := mustEnv("SHARD_MODE", "off"); mode {
+	case "hash":
+		co := shard.New(rdb, shardMembersKey, replicaID())
+		co.TTL, co.Heartbeat = shardTTL, shardTTL/3
+		owned = newDesiredSet(nil)
+		bg.Add(1)
+		go func() {
+			defer bg.Done()
+			followShard(ctx, co, desired, owned)
+		}()
+		log.Printf("shard: hash mode as %s", co.ID)
+	case "standby":
+		lease := shard.NewLease(rdb, shardLeaseKey, replicaID())
+		lease.TTL = shardTTL
+		pub.standby = &standby{lease: lease, window: 2 * shardTTL}
+		bg.Add(1)
+		go func() {
+			defer bg.Done()
+			lease.Run(ctx, func(held bool) {
+				if held {
+					standbyActive.Set(1)
+					pub.takeover(ctx)
+				} else {
+					standbyActive.Set(0)
+				}
+			})
+		}()
+		log.Printf("shard: standby mode as %s", lease.ID)
+	case "off":
+	default:
+		log.Fatalf("unknown SHARD_MODE %q (off, hash, standby)", mode)
+	}
+
+	go func() {
+		http.Handle("/metrics", promhttp.Handler())
+		http.HandleFunc("/subscriptions", func(w http.ResponseWriter, _ *http.Request) {
+			cur, _ := desired.get()
+			mine, _ := owned.get()
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"desired":  cur,
+				"owned":    mine,
+				"sessions": live.status(),
+			})
+		})
+		addr := mustEnv("PROM_ADDR", ":9102")
+		log.Printf("Prometheus at %s/metrics", addr)
+		_ = http.ListenAndServe(addr, nil)
+	}()
+
+	// INGEST_SOURCE=block scans whole blocks for complete coverage (with block
+	// time and index); INGEST_SOURCE=poll uses HTTP only. Both replace the
+	// logsSubscribe source.
+	httpCommitment := commitment
+	if httpCommitment == "processed" {
+		httpCommitment = "confirmed" // getBlock / getSignaturesForAddress do not serve processed
+	}
+	switch mustEnv("INGEST_SOURCE", "ws") {
+	case "block":
+		src := &blockSource{
+			http:       newHTTPClient(ctx),
+			pub:        pub,
+			desired:    owned,
+			commitment: httpCommitment,
+			interval:   time.Duration(envInt("BLOCK_POLL_MS", 1000)) * time.Millisecond,
+			maxRange:   uint64(envInt("BLOCK_MAX_RANGE", 100)),
+			cursor:     uint64(envInt("BLOCK_START_SLOT", 0)),
+		}
+		if envBool("BLOCK_SUBSCRIBE", false) {
+			src.ws = rpc.NewWSClient(wsURLs)
+			src.ws.Start(ctx)
+		}
+		src.run(ctx)
+		return
+	case "replay":
+		files, err := record.Files(mustEnv("REPLAY_FILES", "/data/record/*.ndjson.gz"))
+		if err != nil {
+			log.Fatalf("replay: %v", err)
+		}
+		// Each run dedupes on its own keys, so replaying the same recording
+		// again publishes it again instead of finding it all seen.
+		pub.dedupePrefix = "dedupe:replay:" + time.Now().UTC().Format("20060102T150405.000000000") + ":"
+		src := &replaySource{
+			pub:        pub,
+			commitment: commitment,
+			files:      files,
+			speed:      envFloat("REPLAY_SPEED", 1),
+		}
+		if err := src.run(ctx); err != nil {
+			log.Printf("replay: %v", err)
+		}
+		return
+	case "poll":
+		src := &pollSource{
+			http:        newHTTPClient(ctx),
+			pub:         pub,
+			desired:     owned,
+			commitment:  httpCommitment,
+			minInterval: time.Duration(envInt("POLL_MIN_MS", 1000)) * time.Millisecond,
+			maxInterval: time.Duration(envInt("POLL_MAX_MS", 30000)) * time.Millisecond,
+			maxSigs:     envInt("POLL_MAX_SIGNATURES", 2000),
+			fetchLogs:   envBool("POLL_FETCH_LOGS", true),
+		}
+		src.run(ctx)
+		return
+	}
+
+	in := &ingester{
+		pub:        pub,
+		pool:       pool,
+		commitment: commitment,
+		desired:    owned,
+	}
+	// RECORD_DIR saves every raw notification for later INGEST_SOURCE=replay runs.
+	if dir := os.Getenv("RECORD_DIR"); dir != "" {
+		rec, err := record.NewRecorder(dir)
+		if err != nil {
+			log.Fatalf("record: %v", err)
+		}
+		rec.MaxBytes = int64(envInt("RECORD_MAX_MB", 64)) << 20
+		rec.MaxAge = time.Duration(envInt("RECORD_ROTATE_MIN", 60)) * time.Minute
+		defer rec.Close()
+		in.rec = rec
+	}
+	// After a reconnect, page getSignaturesForAddress back to the last
+	// notification of each watched address and publish what the WS missed.
+	if envBool("GAP_BACKFILL", true) {
+		httpc := newHTTPClient(ctx)
+		in.gaps = newGapTracker(httpc, in.pub, envInt("GAP_MAX_SIGNATURES", 2000))
+	}
+
+	// Hedged mode subscribes on every endpoint at once and merges the streams
+	// through dedupe; otherwise one connection follows the failover pool.
+	if envBool("WS_HEDGE", false) && pool.Len() > 1 {
+		in.merge = newMerger(pool.Endpoints(), time.Duration(envPositiveInt("WS_HEDGE_WINDOW_SEC", 30))*time.Second)
+		in.pub.merge = in.merge
+		go in.merge.run(ctx)
+		var wg sync.WaitGroup
+		for _, ep := range pool.Endpoints() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				in.loop(ctx, func() string { return ep.URL }, newRetryPolicy(rpc.EndpointLabel(ep.URL)))
+			}()
+		}
+		wg.Wait()
+		return
+	}
+	in.loop(ctx, pool.Pick, newRetryPolicy("pool"))
+}
+
+type ingester struct {
+	pub        *publisher
+	pool       *rpc.EndpointPool
+	commitment string
+	desired    *desiredSet
+	merge      *merger     // nil unless hedged
+	gaps       *gapTracker // nil when GAP_BACKFILL=false
+	rec        *record.Recorder
+}
+
+// loop keeps one connection alive, asking next for the endpoint each time,
+// until ctx is cancelled.
+func (in *ingester) loop(ctx context.Context, next func() string, rp *retryPolicy) {
+	for ctx.Err() == nil {
+		rp.attempt()
+		start := time.Now()
+		err := in.runOnce(ctx, next())
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) >= healthyConn {
+			rp.reset()
+		}
+		log.Printf("stream error: %v", err)
+		reconnects.Inc()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(rp.failed()):
 		}
 	}
 }
 
-func runOnce(ctx context.Context, rdb *redis.Client, wsURL, commitment string, programs, accounts []string, dedupeTTL time.Duration) error {
+func (in *ingester) runOnce(ctx context.Context, wsURL string) error {
+	pool, desired := in.pool, in.desired
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 15 * time.Second,
 		ReadBufferSize:   1 << 20,
@@ -122,37 +415,87 @@ func runOnce(ctx context.Context, rdb *redis.Client, wsURL, commitment string, p
 	}
 	conn, _, err := dialer.Dial(wsURL, nil)
 	if err != nil {
+		pool.ReportFailure(wsURL)
 		return err
 	}
 	defer conn.Close()
+	log.Printf("connected to %s", wsURL)
 
-	// Build filter param: either "all" or {"mentions":[...]}
-	var filter any = "all"
-	if len(programs) > 0 {
-		filter = map[string]any{"mentions": programs}
-	} else if len(accounts) > 0 {
-		filter = map[string]any{"mentions": accounts}
-	}
-
-	// Second param is opts object (commitment etc.)
-	opts := map[string]any{"commitment": commitment}
+	sess := newSession(wsURL, conn, in.commitment, in.pub, in.gaps)
+	sess.rec = in.rec
+	live.add(sess)
+	defer live.remove(sess)
+	defer subsActive.DeleteLabelValues(sess.label)
 
-	req := wsReq{
-		Jsonrpc: "2.0",
-		ID:      1,
-		Method:  "logsSubscribe",
-		Params:  []any{filter, opts},
-	}
-	if err := conn.WriteJSON(req); err != nil {
+	cur, changed := desired.get()
+	if err := sess.apply(cur); err != nil {
 		return err
 	}
 
+	// On shutdown, unsubscribe and close; the read loop keeps publishing
+	// whatever arrives until the close handshake ends it.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+		sess.close(2 * time.Second)
+		_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	}()
+
+	// Apply watch-list changes live on this connection. The periodic pass
+	// retries subscriptions the node rejected; the endpoint check drops the
+	// connection when the pool wants to fail over or back.
+	go func() {
+		t := time.NewTicker(30 * time.Second)
+		defer t.Stop()
+		check := time.NewTicker(10 * time.Second)
+		defer check.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-check.C:
+				if in.merge != nil {
+					continue // hedged sessions stay pinned to their endpoint
+				}
+				if next, ok := pool.SwitchTarget(wsURL); ok {
+					log.Printf("failing over %s -> %s", wsURL, next)
+					failovers.Inc()
+					_ = conn.Close()
+					return
+				}
+				continue
+			case <-changed:
+			case <-t.C:
+			}
+			cur, changed = desired.get()
+			if err := sess.apply(cur); err != nil {
+				log.Printf("apply subscriptions: %v", err)
+				_ = conn.Close()
+				return
+			}
+		}
+	}()
+
 	// Keepalive
 	go func() {
 		t := time.NewTicker(20 * time.Second)
 		defer t.Stop()
-		for range t.C {
-			_ = conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(5*time.Second))
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			if err := conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(5*time.Second)); err != nil {
+				return
+			}
 		}
 	}()
 
@@ -166,52 +509,165 @@ func runOnce(ctx context.Context, rdb *redis.Client, wsURL, commitment string, p
 	for {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
+			if ctx.Err() != nil {
+				log.Printf("closed %s", wsURL)
+				return nil
+			}
+			pool.ReportFailure(wsURL)
 			return err
 		}
-		var noti logNoti
-		if err := json.Unmarshal(msg, &noti); err != nil {
-			// ignore non-notifications (e.g. subscribe ack)
-			continue
-		}
-		ingested.Inc()
-
-		sig := noti.Params.Result.Value.Signature
-		slot := noti.Params.Result.Context.Slot
-		if sig == "" {
-			continue
-		}
+		sess.handle(ctx, msg)
+	}
+}
 
-		// Dedupe on signature+slot
-		key := "dedupe:" + sig + ":" + itoa(slot)
-		ok, err := rdb.SetNX(ctx, key, "1", dedupeTTL).Result()
-		if err != nil {
-			log.Printf("redis dedupe err: %v", err)
-			continue
+func exportEndpointScores(ctx context.Context, pool *rpc.EndpointPool) {
+	t := time.NewTicker(5 * time.Second)
+	defer t.Stop()
+	for {
+		for _, sc := range pool.Scores() {
+			label := rpc.EndpointLabel(sc.URL)
+			endpointScore.WithLabelValues(label).Set(sc.Score)
+			endpointLag.WithLabelValues(label).Set(float64(sc.SlotLag))
+			active := 0.0
+			if sc.Active {
+				active = 1
+			}
+			endpointActive.WithLabelValues(label).Set(active)
 		}
-		if !ok {
-			deduped.Inc()
-			continue
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
 		}
+	}
+}
 
-		// Publish to Redis Stream
-		fields := map[string]any{
-			"slot":      slot,
-			"signature": sig,
-			"err":       toJSON(noti.Params.Result.Value.Err),
-			"logs":      strings.Join(noti.Params.Result.Value.Logs, "\n"),
-			"ts":        time.Now().UTC().Format(time.RFC3339Nano),
-		}
-		if _, err := rdb.XAdd(ctx, &redis.XAddArgs{
-			Stream: "sol:logs",
-			Values: fields,
-			MaxLen: 100000, // rolling buffer
-		}).Result(); err != nil {
-			log.Printf("redis xadd err: %v", err)
-			continue
-		}
-		published.Inc()
-		log.Printf("published signature=%s slot=%d", sig, slot)
+type publisher struct {
+	rdb          *redis.Client
+	dedupeTTL    time.Duration
+	dedupePrefix string       // "" = "dedupe:"; replay runs use their own
+	merge        *merger      // nil unless hedged
+	spill        *spill.Queue // nil unless SPILL_DIR is set
+	standby      *standby     // nil unless SHARD_MODE=standby
+}
+
+// logEvent is what lands in sol:logs, whatever source produced it.
+type logEvent struct {
+	Signature  string
+	Slot       uint64
+	Err        any
+	Logs       []string
+	Provider   string // endpoint label, "backfill" or "block"
+	Commitment string // commitment the source observed it at
+	BlockTime  *int64 // block sources only
+	TxIndex    *int   // position within the block; block sources only
+}
+
+// publishLog dedupes a log notification and appends it to the sol:logs stream.
+// provider is the endpoint label that delivered it.
+func (p *publisher) publishLog(ctx context.Context, provider, commitment string, noti logNoti) {
+	if p.merge != nil && noti.Params.Result.Value.Signature != "" {
+		p.merge.observe(provider, noti.Params.Result.Value.Signature)
+	}
+	p.publish(ctx, logEvent{
+		Signature:  noti.Params.Result.Value.Signature,
+		Slot:       noti.Params.Result.Context.Slot,
+		Err:        noti.Params.Result.Value.Err,
+		Logs:       noti.Params.Result.Value.Logs,
+		Provider:   provider,
+		Commitment: commitment,
+	})
+}
+
+// seen reports whether sig was already published (best effort; errors count as unseen).
+func (p *publisher) seen(ctx context.Context, sig string, slot uint64) bool {
+	n, err := p.rdb.Exists(ctx, p.dedupeKey(sig+":"+itoa(slot))).Result()
+	return err == nil && n > 0
+}
+
+// publish reports whether ev was appended, or spilled to disk for later
+// (false if deduped or lost).
+func (p *publisher) publish(ctx context.Context, ev logEvent) bool {
+	if ev.Signature == "" {
+		return false
+	}
+	ctx = context.WithoutCancel(ctx) // a started publish completes during shutdown
+	e := spillEntry{Log: &ev}
+	if p.standby.hold(e) {
+		return false
 	}
+	if p.spilling() {
+		return p.park(e)
+	}
+	ok, err := p.writeLog(ctx, ev, &e.Claimed)
+	if err != nil {
+		return p.park(e)
+	}
+	return ok
+}
+
+// dedupeKey is the Redis key that marks id as published.
+func (p *publisher) dedupeKey(id string) string {
+	if p.dedupePrefix != "" {
+		return p.dedupePrefix + id
+	}
+	return "dedupe:" + id
+}
+
+// claim sets the dedupe key (value: the provider that won) unless this event
+// already holds it from an earlier attempt.
+func (p *publisher) claim(ctx context.Context, key, provider string, claimed *bool) (bool, error) {
+	if *claimed {
+		return true, nil
+	}
+	ok, err := p.rdb.SetNX(ctx, key, provider, p.dedupeTTL).Result()
+	if err != nil {
+		log.Printf("redis dedupe err: %v", err)
+		return false, err
+	}
+	*claimed = ok
+	return ok, nil
+}
+
+// writeLog dedupes ev on signature+slot and appends it to sol:logs. An error
+// means Redis failed and the event was not published.
+func (p *publisher) writeLog(ctx context.Context, ev logEvent, claimed *bool) (bool, error) {
+	ok, err := p.claim(ctx, p.dedupeKey(ev.Signature+":"+itoa(ev.Slot)), ev.Provider, claimed)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		deduped.Inc()
+		return false, nil
+	}
+
+	// Publish to Redis Stream
+	fields := map[string]any{
+		"slot":       ev.Slot,
+		"signature":  ev.Signature,
+		"err":        toJSON(ev.Err),
+		"logs":       strings.Join(ev.Logs, "\n"),
+		"provider":   ev.Provider,
+		"commitment": ev.Commitment,
+		"ts":         time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if ev.BlockTime != nil {
+		fields["block_time"] = *ev.BlockTime
+	}
+	if ev.TxIndex != nil {
+		fields["tx_index"] = *ev.TxIndex
+	}
+	if _, err := p.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: "sol:logs",
+		Values: fields,
+		MaxLen: 100000, // rolling buffer
+	}).Result(); err != nil {
+		log.Printf("redis xadd err: %v", err)
+		return false, err
+	}
+	published.Inc()
+	log.Printf("published signature=%s slot=%d", ev.Signature, ev.Slot)
+	return true, nil
 }
 
 func splitCSV(s string) []string {
@@ -245,4 +701,31 @@ func envInt(key string, def int) int {
 	return def
 }
 
-func backoff(_ error) time.Duration { return 3 * time.Second }
+// envPositiveInt is envInt for settings that must be above zero (durations
+// that drive tickers); other values fall back to def.
+func envPositiveInt(key string, def int) int {
+	n := envInt(key, def)
+	if n <= 0 {
+		log.Printf("%s=%d must be positive; using %d", key, n, def)
+		return def
+	}
+	return n
+}
+
+func envFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+func envBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
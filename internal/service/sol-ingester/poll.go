@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+)
+
+var (
+	pollRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_poll_requests_total",
+		Help: "getSignaturesForAddress calls made by the poll source, by result (ok, error, rate_limited).",
+	}, []string{"result"})
+	pollInterval = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sentinel_poll_interval_seconds",
+		Help: "Current adaptive poll interval per watched address.",
+	}, []string{"address"})
+	pollCooldown = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sentinel_poll_cooldown_seconds",
+		Help: "Pause imposed after the provider rate-limited the poll source (0 = none).",
+	})
+)
+
+// pollCursor is the newest signature seen for one address and when to poll it next.
+type pollCursor struct {
+	sig      string
+	slot     uint64
+	interval time.Duration
+	due      time.Time
+}
+
+// pollSource ingests over plain HTTP for providers without WebSocket access.
+// Each watched address is polled with getSignaturesForAddress until=<newest
+// seen>; the interval halves while an address is busy and grows while it is
+// quiet. A rate-limit response pauses all polling with a growing cooldown.
+type pollSource struct {
+	http        *rpc.HTTPClient
+	pub         *publisher
+	desired     *desiredSet
+	commitment  string // confirmed or finalized
+	minInterval time.Duration
+	maxInterval time.Duration
+	maxSigs     int  // per address per poll
+	fetchLogs   bool // getTransaction for each new signature
+
+	cursors  map[string]*pollCursor
+	cooldown time.Duration
+}
+
+func (p *pollSource) run(ctx context.Context) {
+	p.cursors = map[string]*pollCursor{}
+	warned := false
+	for {
+		cur, changed := p.desired.get()
+		addrs, all := watchedAddresses(cur)
+		if all && !warned {
+			log.Printf("[poll] \"all\" cannot be polled per address; ignoring it")
+			warned = true
+		}
+		p.prune(addrs)
+
+		now := time.Now()
+		var batch []rpc.SignatureInfo
+		for _, a := range addrs {
+			c := p.cursors[a]
+			if c == nil {
+				c = &pollCursor{interval: p.minInterval}
+				p.cursors[a] = c
+			}
+			if now.Before(c.due) {
+				continue
+			}
+			found, err := p.poll(ctx, a, c)
+			if err != nil {
+				if rpc.IsRateLimited(err) {
+					p.rateLimited()
+					break
+				}
+				log.Printf("[poll] %s: %v", a, err)
+				pollRequests.WithLabelValues("error").Inc()
+				c.due = time.Now().Add(c.interval)
+				continue
+			}
+			batch = append(batch, found...)
+		}
+		p.publish(ctx, batch)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-time.After(p.sleep()):
+		}
+	}
+}
+
+// poll fetches what is new for one address (oldest first) and advances its cursor.
+func (p *pollSource) poll(ctx context.Context, addr string, c *pollCursor) ([]rpc.SignatureInfo, error) {
+	const page = 1000
+	var found []rpc.SignatureInfo
+	before := ""
+	for {
+		limit := page
+		if c.sig == "" {
+			limit = 1 // first poll only establishes the starting point
+		}
+		sigs, err := p.http.GetSignaturesForAddressWithOpts(ctx, addr, rpc.SignaturesOpts{
+			Limit:      limit,
+			Before:     before,
+			Until:      c.sig,
+			Commitment: p.commitment,
+		})
+		if err != nil {
+			return nil, err
+		}
+		pollRequests.WithLabelValues("ok").Inc()
+		p.cooldown /= 2
+		found = append(found, sigs...)
+		if c.sig == "" || len(sigs) < limit || len(found) >= p.maxSigs {
+			if len(found) >= p.maxSigs {
+				log.Printf("[poll] %s: more than %d new signatures; older ones are skipped", addr, p.maxSigs)
+			}
+			break
+		}
+		before = sigs[len(sigs)-1].Signature
+	}
+
+	first := c.sig == ""
+	if len(found) > 0 {
+		c.sig, c.slot = found[0].Signature, found[0].Slot
+	}
+	// Busy addresses are polled faster, quiet ones back off.
+	if len(found) > 0 && !first {
+		c.interval = max(p.minInterval, c.interval/2)
+	} else {
+		c.interval = min(p.maxInterval, c.interval*3/2)
+	}
+	c.due = time.Now().Add(c.interval)
+	pollInterval.WithLabelValues(addr).Set(c.interval.Seconds())
+
+	if first {
+		return nil, nil
+	}
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	return found, nil
+}
+
+// publish writes a round's signatures to sol:logs in slot order. A
+// transaction mentioning several watched addresses is published once.
+func (p *pollSource) publish(ctx context.Context, batch []rpc.SignatureInfo) {
+	sort.SliceStable(batch, func(i, j int) bool { return batch[i].Slot < batch[j].Slot })
+	done := map[string]bool{}
+	for _, si := range batch {
+		if done[si.Signature] || p.pub.seen(ctx, si.Signature, si.Slot) {
+			continue
+		}
+		done[si.Signature] = true
+		ev := logEvent{
+			Signature:  si.Signature,
+			Slot:       si.Slot,
+			Err:        si.Err,
+			Provider:   "poll",
+			Commitment: si.ConfirmationStatus,
+			BlockTime:  si.BlockTime,
+		}
+		if ev.Commitment == "" {
+			ev.Commitment = p.commitment
+		}
+		if p.fetchLogs {
+			ev.Logs = fetchLogs(ctx, p.http, si.Signature)
+		}
+		p.pub.publish(ctx, ev)
+	}
+}
+
+func (p *pollSource) rateLimited() {
+	pollRequests.WithLabelValues("rate_limited").Inc()
+	p.cooldown = min(p.maxInterval*4, max(2*time.Second, p.cooldown*2))
+	pollCooldown.Set(p.cooldown.Seconds())
+	log.Printf("[poll] rate limited; pausing %s", p.cooldown)
+}
+
+// sleep is how long until the next address is due, or the rate-limit cooldown.
+func (p *pollSource) sleep() time.Duration {
+	if p.cooldown >= time.Second {
+		return p.cooldown
+	}
+	pollCooldown.Set(0)
+	d := p.maxInterval
+	now := time.Now()
+	for _, c := range p.cursors {
+		d = min(d, c.due.Sub(now))
+	}
+	return max(d, 50*time.Millisecond)
+}
+
+// prune forgets addresses that left the watch list.
+func (p *pollSource) prune(addrs []string) {
+	keep := map[string]bool{}
+	for _, a := range addrs {
+		keep[a] = true
+	}
+	for a := range p.cursors {
+		if !keep[a] {
+			delete(p.cursors, a)
+			pollInterval.DeleteLabelValues(a)
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rileyafox/solana-sentinel/internal/record"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+	"github.com/rileyafox/solana-sentinel/internal/subs"
+)
+
+// wsFrame covers both shapes the node sends us: request acks (id + result/error)
+// and subscription notifications (method + params).
+type wsFrame struct {
+	ID     *int            `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Method string `json:"method"`
+	Params *struct {
+		Subscription int `json:"subscription"`
+	} `json:"params"`
+}
+
+// liveSub is the per-connection state of one configured subscription.
+type liveSub struct {
+	subs.Subscription
+	srvID    int // server-assigned subscription number, valid once acked
+	acked    bool
+	removed  bool // unsubscribe requested before the ack arrived
+	events   uint64
+	lastSlot uint64
+}
+
+type pendingReq struct {
+	sub         *liveSub
+	unsubscribe bool
+}
+
+// session multiplexes the ingester's subscriptions over one WebSocket
+// connection, applying additions and removals without reconnecting.
+type session struct {
+	endpoint   string
+	label      string // endpoint without secrets, for metrics and provider attribution
+	conn       *websocket.Conn
+	commitment string
+	pub        *publisher
+	gaps       *gapTracker
+	rec        *record.Recorder // nil unless RECORD_DIR is set
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]pendingReq
+	subs    map[string]*liveSub // by Subscription.ID
+	bySrv   map[int]*liveSub    // by server subscription number
+}
+
+func newSession(endpoint string, conn *websocket.Conn, commitment string, pub *publisher, gaps *gapTracker) *session {
+	return &session{
+		endpoint:   endpoint,
+		label:      rpc.EndpointLabel(endpoint),
+		conn:       conn,
+		commitment: commitment,
+		pub:        pub,
+		gaps:       gaps,
+		nextID:     1,
+		pending:    map[int]pendingReq{},
+		subs:       map[string]*liveSub{},
+		bySrv:      map[int]*liveSub{},
+	}
+}
+
+// apply diffs desired against what is live on this connection and issues
+// the subscribe/unsubscribe calls needed to converge.
+func (s *session) apply(desired []subs.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	want := make(map[string]subs.Subscription, len(desired))
+	for _, d := range desired {
+		want[d.ID] = d
+	}
+	for id, ls := range s.subs {
+		if d, ok := want[id]; ok && d.Equal(ls.Subscription) {
+			continue
+		}
+		if err := s.unsubscribeLocked(ls); err != nil {
+			return err
+		}
+	}
+	for id, d := range want {
+		if _, ok := s.subs[id]; ok {
+			continue
+		}
+		if err := s.subscribeLocked(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close unsubscribes everything on the connection, waits up to wait for the
+// node to acknowledge, and starts the WebSocket close handshake.
+func (s *session) close(wait time.Duration) {
+	s.mu.Lock()
+	for _, ls := range s.subs {
+		_ = s.unsubscribeLocked(ls)
+	}
+	s.mu.Unlock()
+
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		n := len(s.pending)
+		s.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	_ = s.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "shutdown"), time.Now().Add(time.Second))
+}
+
+// subscribeCall maps a configured subscription to its pubsub method and params.
+func subscribeCall(sub subs.Subscription, commitment string) (string, []any) {
+	opts := map[string]any{"commitment": commitment}
+	switch sub.Kind {
+	case subs.KindAccount:
+		if sub.Encoding != "" {
+			opts["encoding"] = sub.Encoding
+		}
+		return "accountSubscribe", []any{sub.Address, opts}
+	case subs.KindProgram:
+		if sub.Encoding != "" {
+			opts["encoding"] = sub.Encoding
+		}
+		if len(sub.Filters) > 0 {
+			opts["filters"] = sub.Filters
+		}
+		return "programSubscribe", []any{sub.Address, opts}
+	default:
+		var filter any = "all"
+		if sub.Address != "all" {
+			filter = map[string]any{"mentions": []string{sub.Address}}
+		}
+		return "logsSubscribe", []any{filter, opts}
+	}
+}
+
+func unsubscribeMethod(kind string) string {
+	switch kind {
+	case subs.KindAccount:
+		return "accountUnsubscribe"
+	case subs.KindProgram:
+		return "programUnsubscribe"
+	default:
+		return "logsUnsubscribe"
+	}
+}
+
+func (s *session) commitmentFor(sub subs.Subscription) string {
+	if sub.Commitment != "" {
+		return sub.Commitment
+	}
+	return s.commitment
+}
+
+func (s *session) subscribeLocked(sub subs.Subscription) error {
+	method, params := subscribeCall(sub, s.commitmentFor(sub))
+
+	ls := &liveSub{Subscription: sub}
+	id := s.nextID
+	s.nextID++
+	s.pending[id] = pendingReq{sub: ls}
+	s.subs[sub.ID] = ls
+
+	return s.conn.WriteJSON(wsReq{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func (s *session) unsubscribeLocked(ls *liveSub) error {
+	delete(s.subs, ls.ID)
+	if !ls.acked {
+		ls.removed = true
+		return nil
+	}
+	delete(s.bySrv, ls.srvID)
+	s.updateActiveLocked()
+	return s.sendUnsubscribeLocked(ls)
+}
+
+func (s *session) sendUnsubscribeLocked(ls *liveSub) error {
+	id := s.nextID
+	s.nextID++
+	s.pending[id] = pendingReq{sub: ls, unsubscribe: true}
+	return s.conn.WriteJSON(wsReq{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Method:  unsubscribeMethod(ls.Kind),
+		Params:  []any{ls.srvID},
+	})
+}
+
+// handle processes one frame read from the connection.
+func (s *session) handle(ctx context.Context, msg []byte) {
+	var f wsFrame
+	if err := json.Unmarshal(msg, &f); err != nil {
+		return
+	}
+	if f.ID != nil {
+		if ls := s.ack(*f.ID, &f); ls != nil {
+			s.gaps.resubscribed(ctx, ls.Subscription)
+		}
+		return
+	}
+	if f.Params == nil {
+		return
+	}
+	if s.rec != nil {
+		s.record(f.Params.Subscription, msg)
+	}
+
+	switch f.Method {
+	case "logsNotification":
+		var noti logNoti
+		if err := json.Unmarshal(msg, &noti); err != nil {
+			return
+		}
+		ingested.Inc()
+		ls := s.observe(f.Params.Subscription, noti.Params.Result.Context.Slot)
+		commitment := s.commitment
+		if ls != nil {
+			commitment = s.commitmentFor(ls.Subscription)
+			s.gaps.seen(ls.Subscription, noti.Params.Result.Value.Signature, noti.Params.Result.Context.Slot)
+		}
+		s.pub.publishLog(ctx, s.label, commitment, noti)
+
+	case "accountNotification":
+		var noti rpc.AccountMsg
+		if err := json.Unmarshal(msg, &noti); err != nil {
+			return
+		}
+		accountUpdates.Inc()
+		ls := s.observe(f.Params.Subscription, noti.Params.Result.Context.Slot)
+		if ls == nil {
+			return // unsubscribed; the pubkey is only known through the subscription
+		}
+		s.pub.publishAccount(ctx, accountEvent{
+			Pubkey:       ls.Address,
+			Slot:         noti.Params.Result.Context.Slot,
+			Value:        noti.Params.Result.Value,
+			Subscription: ls.ID,
+			Provider:     s.label,
+		})
+
+	case "programNotification":
+		var noti rpc.ProgramMsg
+		if err := json.Unmarshal(msg, &noti); err != nil {
+			return
+		}
+		accountUpdates.Inc()
+		ls := s.observe(f.Params.Subscription, noti.Params.Result.Context.Slot)
+		if ls == nil {
+			return
+		}
+		s.pub.publishAccount(ctx, accountEvent{
+			Pubkey:       noti.Params.Result.Value.Pubkey,
+			Slot:         noti.Params.Result.Context.Slot,
+			Value:        noti.Params.Result.Value.Account,
+			Program:      ls.Address,
+			Subscription: ls.ID,
+			Provider:     s.label,
+		})
+	}
+}
+
+// record saves a raw notification with the subscription it belongs to, so a
+// replay can resolve it without the original acks.
+func (s *session) record(srvID int, msg []byte) {
+	rec := record.Record{At: time.Now().UTC(), Endpoint: s.label, Msg: append(json.RawMessage(nil), msg...)}
+	s.mu.Lock()
+	if ls := s.bySrv[srvID]; ls != nil {
+		rec.Kind, rec.Address = ls.Kind, ls.Address
+	}
+	s.mu.Unlock()
+	if err := s.rec.Write(rec); err != nil {
+		log.Printf("record: %v", err)
+	}
+}
+
+// observe counts a notification against its subscription and returns it
+// (nil if the subscription is no longer live).
+func (s *session) observe(srvID int, slot uint64) *liveSub {
+	s.mu.Lock()
+	ls := s.bySrv[srvID]
+	if ls != nil {
+		ls.events++
+		if slot > ls.lastSlot {
+			ls.lastSlot = slot
+		}
+	}
+	s.mu.Unlock()
+	if ls != nil {
+		subEvents.WithLabelValues(ls.ID).Inc()
+	}
+	return ls
+}
+
+// ack resolves a pending request and returns the subscription it activated, if any.
+func (s *session) ack(id int, f *wsFrame) *liveSub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[id]
+	if !ok {
+		return nil
+	}
+	delete(s.pending, id)
+	ls := p.sub
+
+	if p.unsubscribe {
+		if f.Error != nil {
+			log.Printf("unsubscribe %s failed: %s", ls.ID, f.Error.Message)
+		}
+		return nil
+	}
+	if f.Error != nil {
+		log.Printf("subscribe %s failed: code=%d %s", ls.ID, f.Error.Code, f.Error.Message)
+		if s.subs[ls.ID] == ls {
+			delete(s.subs, ls.ID) // retried on the next apply
+		}
+		return nil
+	}
+	if err := json.Unmarshal(f.Result, &ls.srvID); err != nil {
+		log.Printf("subscribe %s: bad ack %s", ls.ID, string(f.Result))
+		return nil
+	}
+	ls.acked = true
+	if ls.removed {
+		if err := s.sendUnsubscribeLocked(ls); err != nil {
+			log.Printf("unsubscribe %s: %v", ls.ID, err)
+		}
+		return nil
+	}
+	s.bySrv[ls.srvID] = ls
+	log.Printf("subscribed %s (subscription=%d) on %s", ls.ID, ls.srvID, s.endpoint)
+	s.updateActiveLocked()
+	return ls
+}
+
+func (s *session) updateActiveLocked() {
+	subsActive.WithLabelValues(s.label).Set(float64(len(s.bySrv)))
+}
+
+type subStatus struct {
+	ID           string `json:"id"`
+	Kind         string `json:"kind"`
+	Address      string `json:"address"`
+	Subscription int    `json:"subscription"`
+	Active       bool   `json:"active"`
+	Events       uint64 `json:"events"`
+	LastSlot     uint64 `json:"last_slot"`
+}
+
+type sessionStatus struct {
+	Endpoint      string      `json:"endpoint"`
+	Subscriptions []subStatus `json:"subscriptions"`
+}
+
+func (s *session) status() sessionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := sessionStatus{Endpoint: s.endpoint, Subscriptions: make([]subStatus, 0, len(s.subs))}
+	for _, ls := range s.subs {
+		out.Subscriptions = append(out.Subscriptions, subStatus{
+			ID:           ls.ID,
+			Kind:         ls.Kind,
+			Address:      ls.Address,
+			Subscription: ls.srvID,
+			Active:       ls.acked,
+			Events:       ls.events,
+			LastSlot:     ls.lastSlot,
+		})
+	}
+	sort.Slice(out.Subscriptions, func(i, j int) bool { return out.Subscriptions[i].ID < out.Subscriptions[j].ID })
+	return out
+}
+
+// sessionSet tracks connected sessions for the /subscriptions status endpoint.
+type sessionSet struct {
+	mu sync.Mutex
+	m  map[*session]struct{}
+}
+
+func (ss *sessionSet) add(s *session) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.m == nil {
+		ss.m = map[*session]struct{}{}
+	}
+	ss.m[s] = struct{}{}
+}
+
+func (ss *sessionSet) remove(s *session) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.m, s)
+}
+
+func (ss *sessionSet) status() []sessionStatus {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	out := make([]sessionStatus, 0, len(ss.m))
+	for s := range ss.m {
+		out = append(out, s.status())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Endpoint < out[j].Endpoint })
+	return out
+}
+
+// desiredSet holds the current watch list and wakes sessions when it changes.
+type desiredSet struct {
+	mu      sync.Mutex
+	subs    []subs.Subscription
+	changed chan struct{}
+}
+
+func newDesiredSet(initial []subs.Subscription) *desiredSet {
+	return &desiredSet{subs: initial, changed: make(chan struct{})}
+}
+
+// get returns the current set and a channel that is closed on the next change.
+func (d *desiredSet) get() ([]subs.Subscription, <-chan struct{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.subs, d.changed
+}
+
+func (d *desiredSet) set(s []subs.Subscription) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subs = s
+	close(d.changed)
+	d.changed = make(chan struct{})
+	log.Printf("watch list updated: %d subscriptions", len(s))
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rileyafox/solana-sentinel/internal/shard"
+	"github.com/rileyafox/solana-sentinel/internal/subs"
+)
+
+// Redis keys shared by the replicas of one deployment.
+const (
+	shardMembersKey = "sol:ingesters"        // sorted set of live replicas (SHARD_MODE=hash)
+	shardLeaseKey   = "sol:ingesters:active" // active replica (SHARD_MODE=standby)
+)
+
+var (
+	shardMembers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sentinel_shard_members",
+		Help: "Live sol-ingester replicas sharing the watch list.",
+	})
+	shardOwned = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sentinel_shard_owned_subscriptions",
+		Help: "Watch-list entries this replica subscribes to.",
+	})
+	standbyActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sentinel_standby_active",
+		Help: "1 while this replica holds the active lease (SHARD_MODE=standby).",
+	})
+	standbyHeld = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sentinel_standby_held_events_total",
+		Help: "Events held back instead of published while in standby.",
+	})
+)
+
+// replicaID names this process in the shard group; compose --scale gives
+// each container its own hostname.
+func replicaID() string {
+	if id := os.Getenv("SHARD_ID"); id != "" {
+		return id
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// followShard keeps owned to the entries of desired that the ring assigns to
+// this replica, re-filtering when the watch list or the membership changes.
+func followShard(ctx context.Context, co *shard.Coordinator, desired, owned *desiredSet) {
+	wake := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	defer wg.Wait() // co.Run leaves the member set on the way out
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		co.Run(ctx, func(members []string) {
+			shardMembers.Set(float64(len(members)))
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	// Give the first heartbeat a chance, so a new replica does not briefly
+	// subscribe to everything.
+	select {
+	case <-ctx.Done():
+		return
+	case <-wake:
+	case <-time.After(co.Heartbeat):
+	}
+
+	// Entries that move to another replica stay subscribed here for a couple
+	// of heartbeats, so the handover overlaps (dedupe hides it) instead of
+	// leaving a hole until the new owner notices.
+	grace := 2 * co.Heartbeat
+	handoff := map[string]time.Time{}
+	var last []subs.Subscription
+	for first := true; ; first = false {
+		cur, changed := desired.get()
+		var mine []subs.Subscription
+		mine, handoff = ownedSubs(co.Owns, cur, last, handoff, time.Now(), grace)
+		if first || !sameSubs(mine, last) {
+			log.Printf("shard: own %d of %d subscriptions (%d handing over)", len(mine)-len(handoff), len(cur), len(handoff))
+			owned.set(mine)
+			last = mine
+			shardOwned.Set(float64(len(mine) - len(handoff)))
+		}
+
+		var expire <-chan time.Time
+		if len(handoff) > 0 {
+			expire = time.After(co.Heartbeat)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-wake:
+		case <-expire:
+		}
+	}
+}
+
+// ownedSubs picks the entries of cur this replica subscribes to: the ones
+// owns accepts, plus ones it had in last that moved away less than grace
+// ago. handoff maps entries being handed over to when they stop; the
+// updated map is returned.
+func ownedSubs(owns func(id string) bool, cur, last []subs.Subscription, handoff map[string]time.Time, now time.Time, grace time.Duration) ([]subs.Subscription, map[string]time.Time) {
+	had := map[string]bool{}
+	for _, s := range last {
+		had[s.ID] = true
+	}
+	next := map[string]time.Time{}
+	var mine []subs.Subscription
+	for _, s := range cur {
+		if owns(s.ID) {
+			mine = append(mine, s)
+			continue
+		}
+		until, moving := handoff[s.ID]
+		if !moving && had[s.ID] {
+			until, moving = now.Add(grace), true
+		}
+		if moving && now.Before(until) {
+			next[s.ID] = until
+			mine = append(mine, s)
+		}
+	}
+	return mine, next
+}
+
+func sameSubs(a, b []subs.Subscription) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// maxHeld bounds the standby buffer regardless of the window.
+const maxHeld = 100000
+
+// standby holds events back while another replica is active. The last
+// window of them is kept, and published (through dedupe) on takeover, to
+// cover the time between the old holder dying and its lease expiring.
+// Only a lease Redis reports as held elsewhere counts: while Redis is
+// unreachable no replica can be sure another is active, so all of them
+// publish, which parks events in the spill queue until Redis is back.
+type standby struct {
+	lease  *shard.Lease
+	window time.Duration
+
+	mu   sync.Mutex
+	held []heldEvent
+}
+
+type heldEvent struct {
+	at time.Time
+	e  spillEntry
+}
+
+// hold buffers e and reports true if another replica is active. A nil
+// standby never holds.
+func (s *standby) hold(e spillEntry) bool {
+	if s == nil || !s.lease.HeldElsewhere() {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.held = append(s.held, heldEvent{at: now, e: e})
+	i := 0
+	for i < len(s.held) && (now.Sub(s.held[i].at) > s.window || len(s.held)-i > maxHeld) {
+		i++
+	}
+	s.held = s.held[i:]
+	standbyHeld.Inc()
+	return true
+}
+
+func (s *standby) take() []heldEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	held := s.held
+	s.held = nil
+	return held
+}
+
+// takeover publishes what was held while in standby, oldest first.
+func (p *publisher) takeover(ctx context.Context) {
+	held := p.standby.take()
+	n := 0
+	for _, h := range held {
+		var ok bool
+		switch {
+		case h.e.Log != nil:
+			ok = p.publish(ctx, *h.e.Log)
+		case h.e.Account != nil:
+			ok = p.publishAccount(ctx, *h.e.Account)
+		}
+		if ok {
+			n++
+		}
+	}
+	log.Printf("standby: active; published %d of %d held event(s)", n, len(held))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rileyafox/solana-sentinel/internal/shard"
+	"github.com/rileyafox/solana-sentinel/internal/subs"
+)
+
+func watchList(t *testing.T, n int) []subs.Subscription {
+	t.Helper()
+	out := make([]subs.Subscription, n)
+	for i := range out {
+		s, err := subs.Subscription{Kind: subs.KindLogs, Address: fmt.Sprintf("Addr%d", i)}.Normalize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = s
+	}
+	return out
+}
+
+func ownerIs(r *shard.Ring, id string) func(string) bool {
+	return func(key string) bool { return r.Owner(key) == id }
+}
+
+// Two replicas on the same member list subscribe to disjoint parts of the
+// watch list that together cover all of it.
+func TestOwnedSubsDisjoint(t *testing.T) {
+	cur := watchList(t, 500)
+	ring := shard.NewRing([]string{"a", "b"}, 64)
+	now := time.Now()
+	mineA, _ := ownedSubs(ownerIs(ring, "a"), cur, nil, nil, now, time.Minute)
+	mineB, _ := ownedSubs(ownerIs(ring, "b"), cur, nil, nil, now, time.Minute)
+
+	seen := map[string]string{}
+	for _, s := range mineA {
+		seen[s.ID] = "a"
+	}
+	for _, s := range mineB {
+		if seen[s.ID] != "" {
+			t.Fatalf("%s subscribed on both replicas", s.ID)
+		}
+		seen[s.ID] = "b"
+	}
+	if len(seen) != len(cur) {
+		t.Fatalf("%d of %d subscriptions covered", len(seen), len(cur))
+	}
+	if len(mineA) == 0 || len(mineB) == 0 {
+		t.Fatalf("one replica got nothing: %d / %d", len(mineA), len(mineB))
+	}
+}
+
+// When b joins, a keeps what moved for the grace period, then drops it.
+func TestOwnedSubsHandoff(t *testing.T) {
+	cur := watchList(t, 200)
+	alone := shard.NewRing([]string{"a"}, 64)
+	both := shard.NewRing([]string{"a", "b"}, 64)
+	grace := 10 * time.Second
+	t0 := time.Now()
+
+	last, handoff := ownedSubs(ownerIs(alone, "a"), cur, nil, nil, t0, grace)
+	if len(last) != len(cur) || len(handoff) != 0 {
+		t.Fatalf("alone: own %d of %d, %d handing over", len(last), len(cur), len(handoff))
+	}
+
+	mine, handoff := ownedSubs(ownerIs(both, "a"), cur, last, handoff, t0, grace)
+	if len(mine) != len(cur) || len(handoff) == 0 {
+		t.Fatalf("during grace: own %d of %d, %d handing over", len(mine), len(cur), len(handoff))
+	}
+
+	mine, handoff = ownedSubs(ownerIs(both, "a"), cur, mine, handoff, t0.Add(grace+time.Second), grace)
+	if len(handoff) != 0 {
+		t.Fatalf("after grace: %d still handing over", len(handoff))
+	}
+	for _, s := range mine {
+		if both.Owner(s.ID) != "a" {
+			t.Fatalf("after grace: still subscribed to %s, owned by %s", s.ID, both.Owner(s.ID))
+		}
+	}
+}
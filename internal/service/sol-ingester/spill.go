@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	spillBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sentinel_spill_bytes",
+		Help: "Bytes of events waiting on disk for Redis to come back.",
+	})
+	spillEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_spill_events_total",
+		Help: "Events written to the spill queue (spilled), replayed into Redis (drained), or lost because it was full (dropped).",
+	}, []string{"op"})
+)
+
+// spillEntry is one event parked on disk (SPILL_DIR) while Redis is down.
+type spillEntry struct {
+	Log     *logEvent     `json:"log,omitempty"`
+	Account *accountEvent `json:"account,omitempty"`
+	Claimed bool          `json:"claimed,omitempty"` // dedupe key was set before XADD failed
+}
+
+// spilling reports whether events are queued on disk. New events then queue
+// behind them so the streams keep their order.
+func (p *publisher) spilling() bool {
+	return p.spill != nil && p.spill.Pending() > 0
+}
+
+// park appends e to the spill queue; false if there is none or it is full.
+func (p *publisher) park(e spillEntry) bool {
+	if p.spill == nil {
+		return false
+	}
+	b, err := json.Marshal(e)
+	if err == nil {
+		err = p.spill.Append(b)
+	}
+	if err != nil {
+		spillEvents.WithLabelValues("dropped").Inc()
+		log.Printf("spill: %v; event dropped", err)
+		return false
+	}
+	spillEvents.WithLabelValues("spilled").Inc()
+	spillBytes.Set(float64(p.spill.Pending()))
+	return true
+}
+
+// drainSpill replays parked events, oldest first, whenever Redis answers.
+// An event whose XADD fails again stays at the head of the queue.
+func (p *publisher) drainSpill(ctx context.Context) {
+	spillBytes.Set(float64(p.spill.Pending()))
+	// The head entry as last attempted, so a dedupe key set by a failed
+	// attempt is not mistaken for an earlier publish on the retry.
+	var retryRaw string
+	var retry spillEntry
+
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if p.spill.Pending() == 0 || p.rdb.Ping(ctx).Err() != nil {
+			continue
+		}
+		n, err := p.spill.Drain(func(b []byte) error {
+			var e spillEntry
+			if string(b) == retryRaw {
+				e = retry
+			} else if err := json.Unmarshal(b, &e); err != nil {
+				log.Printf("spill: skip bad entry: %v", err)
+				return nil
+			}
+			var err error
+			switch {
+			case e.Log != nil:
+				_, err = p.writeLog(ctx, *e.Log, &e.Claimed)
+			case e.Account != nil:
+				_, err = p.writeAccount(ctx, *e.Account, &e.Claimed)
+			}
+			if err != nil {
+				retryRaw, retry = string(b), e
+				return err
+			}
+			retryRaw = ""
+			spillEvents.WithLabelValues("drained").Inc()
+			spillBytes.Set(float64(p.spill.Pending()))
+			return nil
+		})
+		if n > 0 || err != nil {
+			log.Printf("spill: drained %d event(s), %d bytes left (err=%v)", n, p.spill.Pending(), err)
+		}
+		spillBytes.Set(float64(p.spill.Pending()))
+	}
+}
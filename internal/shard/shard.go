@@ -0,0 +1,226 @@
+// Package shard coordinates replicas of a service through Redis: a heartbeat
+// membership list with a consistent-hash ring for dividing work, and a
+// single-holder lease for active/standby setups.
+package shard
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Ring maps keys to members with consistent hashing, so a member joining or
+// leaving only moves about 1/n of the keys.
+type Ring struct {
+	points []uint32
+	owner  map[uint32]string
+}
+
+// NewRing places vnodes points per member on the ring.
+func NewRing(members []string, vnodes int) *Ring {
+	r := &Ring{owner: map[uint32]string{}}
+	for _, m := range members {
+		for i := 0; i < vnodes; i++ {
+			h := hash(m + "#" + strconv.Itoa(i))
+			r.points = append(r.points, h)
+			r.owner[h] = m
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// Owner returns the member responsible for key ("" on an empty ring).
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owner[r.points[i]]
+}
+
+func hash(s string) uint32 {
+	sum := sha1.Sum([]byte(s))
+	return binary.BigEndian.Uint32(sum[:4])
+}
+
+// Coordinator keeps this replica in a Redis sorted set of live members
+// (score = lease expiry) and tracks who else is alive. A member that misses
+// heartbeats for TTL drops out and its keys move to the others.
+type Coordinator struct {
+	RDB       *redis.Client
+	Key       string
+	ID        string
+	TTL       time.Duration
+	Heartbeat time.Duration
+	VNodes    int
+
+	mu      sync.Mutex
+	members []string
+	ring    *Ring
+}
+
+func New(rdb *redis.Client, key, id string) *Coordinator {
+	return &Coordinator{RDB: rdb, Key: key, ID: id, TTL: 15 * time.Second, Heartbeat: 5 * time.Second, VNodes: 64}
+}
+
+// Run heartbeats until ctx ends, calling onChange whenever the member list
+// changes, then leaves the set so the others rebalance right away.
+func (c *Coordinator) Run(ctx context.Context, onChange func(members []string)) {
+	t := time.NewTicker(c.Heartbeat)
+	defer t.Stop()
+	for {
+		if members, err := c.beat(ctx); err != nil {
+			log.Printf("shard: heartbeat: %v", err)
+		} else if c.update(members) {
+			log.Printf("shard: %d member(s): %v", len(members), members)
+			onChange(members)
+		}
+		select {
+		case <-ctx.Done():
+			leave, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			_ = c.RDB.ZRem(leave, c.Key, c.ID).Err()
+			cancel()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// beat renews this member, expires dead ones, and returns the live set.
+func (c *Coordinator) beat(ctx context.Context) ([]string, error) {
+	now := time.Now()
+	pipe := c.RDB.TxPipeline()
+	pipe.ZAdd(ctx, c.Key, redis.Z{Score: float64(now.Add(c.TTL).UnixMilli()), Member: c.ID})
+	pipe.ZRemRangeByScore(ctx, c.Key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+	list := pipe.ZRange(ctx, c.Key, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	members := list.Val()
+	sort.Strings(members)
+	return members, nil
+}
+
+func (c *Coordinator) update(members []string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if slices.Equal(c.members, members) {
+		return false
+	}
+	c.members = members
+	c.ring = NewRing(members, c.VNodes)
+	return true
+}
+
+// Members is the live set from the last successful heartbeat.
+func (c *Coordinator) Members() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.members
+}
+
+// Owns reports whether key belongs to this replica. Until the first heartbeat
+// succeeds every key is owned: duplicates are cheaper than holes.
+func (c *Coordinator) Owns(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ring == nil {
+		return true
+	}
+	return c.ring.Owner(key) == c.ID
+}
+
+// renewScript extends the lease only if this replica still holds it.
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseScript deletes the lease only if this replica holds it.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Lease is a single-holder lock with a TTL, renewed while held. Replicas that
+// do not hold it retry and take over once the holder stops renewing.
+type Lease struct {
+	RDB *redis.Client
+	Key string
+	ID  string
+	TTL time.Duration
+
+	held  atomic.Bool
+	other atomic.Bool // Redis answered that another replica holds it
+}
+
+func NewLease(rdb *redis.Client, key, id string) *Lease {
+	return &Lease{RDB: rdb, Key: key, ID: id, TTL: 10 * time.Second}
+}
+
+// Held reports whether this replica held the lease at the last check.
+func (l *Lease) Held() bool { return l.held.Load() }
+
+// HeldElsewhere reports whether Redis said at the last check that another
+// replica holds the lease. It is false while Redis cannot be reached, when
+// nobody may be active.
+func (l *Lease) HeldElsewhere() bool { return l.other.Load() }
+
+// Run acquires and renews the lease every TTL/3 until ctx ends, calling
+// onChange when this replica gains or loses it. A renewal that cannot reach
+// Redis counts as lost, since another replica may take over at expiry.
+func (l *Lease) Run(ctx context.Context, onChange func(held bool)) {
+	t := time.NewTicker(l.TTL / 3)
+	defer t.Stop()
+	for {
+		held := l.try(ctx)
+		if held != l.held.Swap(held) {
+			log.Printf("shard: lease %s held=%v", l.Key, held)
+			onChange(held)
+		}
+		select {
+		case <-ctx.Done():
+			if l.held.Swap(false) {
+				rel, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				_ = releaseScript.Run(rel, l.RDB, []string{l.Key}, l.ID).Err()
+				cancel()
+			}
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (l *Lease) try(ctx context.Context) bool {
+	if l.held.Load() {
+		n, err := renewScript.Run(ctx, l.RDB, []string{l.Key}, l.ID, l.TTL.Milliseconds()).Int()
+		if err != nil {
+			log.Printf("shard: renew lease: %v", err)
+		}
+		// Lost or expired: the next try finds out who holds it now.
+		l.other.Store(false)
+		return err == nil && n == 1
+	}
+	ok, err := l.RDB.SetNX(ctx, l.Key, l.ID, l.TTL).Result()
+	if err != nil {
+		log.Printf("shard: acquire lease: %v", err)
+	}
+	l.other.Store(err == nil && !ok)
+	return err == nil && ok
+}
@@ -0,0 +1,73 @@
+package shard
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Replicas that see the same member list must split the watch list between
+// them: every subscription owned by exactly one, none by both.
+func TestReplicasOwnDisjointSets(t *testing.T) {
+	a := New(nil, "sol:ingesters", "ingester-a")
+	b := New(nil, "sol:ingesters", "ingester-b")
+	members := []string{"ingester-a", "ingester-b"}
+	a.update(members)
+	b.update(members)
+
+	var ownA, ownB int
+	for i := 0; i < 2000; i++ {
+		id := fmt.Sprintf("logs:addr-%d", i)
+		inA, inB := a.Owns(id), b.Owns(id)
+		switch {
+		case inA && inB:
+			t.Fatalf("%s owned by both replicas", id)
+		case !inA && !inB:
+			t.Fatalf("%s owned by neither replica", id)
+		case inA:
+			ownA++
+		default:
+			ownB++
+		}
+	}
+	// 64 vnodes each keep the split roughly even.
+	if ownA < 600 || ownB < 600 {
+		t.Errorf("uneven split: %d / %d", ownA, ownB)
+	}
+
+	// When b leaves, a takes everything.
+	a.update([]string{"ingester-a"})
+	for i := 0; i < 2000; i++ {
+		if id := fmt.Sprintf("logs:addr-%d", i); !a.Owns(id) {
+			t.Fatalf("%s not owned by the only replica", id)
+		}
+	}
+}
+
+// A joining member only takes keys over; it never moves keys between the
+// members that were already there.
+func TestRingJoinMovesOnlyToNewMember(t *testing.T) {
+	before := NewRing([]string{"a", "b"}, 64)
+	after := NewRing([]string{"a", "b", "c"}, 64)
+	moved := 0
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("logs:addr-%d", i)
+		was, is := before.Owner(key), after.Owner(key)
+		if was != is {
+			if is != "c" {
+				t.Fatalf("%s moved from %s to %s", key, was, is)
+			}
+			moved++
+		}
+	}
+	if moved < 500 || moved > 1500 {
+		t.Errorf("%d of 3000 keys moved to the new member, want about 1000", moved)
+	}
+}
+
+// Before the first heartbeat every key is owned: duplicates beat holes.
+func TestOwnsEverythingBeforeFirstHeartbeat(t *testing.T) {
+	c := New(nil, "sol:ingesters", "ingester-a")
+	if !c.Owns("logs:anything") {
+		t.Fatal("key not owned before the first heartbeat")
+	}
+}
@@ -0,0 +1,203 @@
+package sigwatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+)
+
+// Statuses reported by Watch, in order. Expired ends a watch whose
+// transaction never landed before its blockhash ran out.
+const (
+	StatusProcessed = "processed"
+	StatusConfirmed = "confirmed"
+	StatusFinalized = "finalized"
+	StatusExpired   = "expired"
+)
+
+var levels = []string{StatusProcessed, StatusConfirmed, StatusFinalized}
+
+func rank(commitment string) int {
+	for i, l := range levels {
+		if l == commitment {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+var ErrInvalid = errors.New("invalid signature watch")
+
+// Update is one status transition.
+type Update struct {
+	Signature string
+	Status    string
+	Slot      uint64
+	Err       string // transaction error as JSON; "" on success
+	At        time.Time
+}
+
+// Watcher follows a signature through processed, confirmed and finalized.
+// One signatureSubscribe at the target commitment gives the fast path to the
+// final status; getSignatureStatuses polling reports the levels before it and
+// covers missed notifications and reconnects.
+type Watcher struct {
+	WS   *rpc.WSClient
+	HTTP *rpc.HTTPClient
+
+	PollInterval time.Duration
+	ExpirySlots  uint64 // used when the caller has no lastValidBlockHeight
+}
+
+func New(ws *rpc.WSClient, http *rpc.HTTPClient) *Watcher {
+	return &Watcher{
+		WS:           ws,
+		HTTP:         http,
+		PollInterval: 2 * time.Second,
+		ExpirySlots:  151, // blockhashes are valid for 150 blocks after the one they name
+	}
+}
+
+type observation struct {
+	commitment string
+	slot       uint64
+	err        any
+}
+
+// Watch calls send for every status the signature reaches, up to and
+// including until ("" = finalized), then returns nil. If the signature has
+// not been confirmed by the time its blockhash expires, it sends
+// StatusExpired and returns nil. A send error ends the watch.
+func (w *Watcher) Watch(ctx context.Context, sig, until string, lastValidBlockHeight uint64, send func(Update) error) error {
+	if sig == "" {
+		return fmt.Errorf("%w: signature required", ErrInvalid)
+	}
+	if until == "" {
+		until = StatusFinalized
+	}
+	target := rank(until)
+	if target == 0 {
+		return fmt.Errorf("%w: unknown commitment %q", ErrInvalid, until)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// A subscription per level would cost a connection each, so only the
+	// target is subscribed; the poll reports the levels on the way there.
+	ch, err := w.WS.SubscribeSignature(ctx, sig, until)
+	if err != nil {
+		return err
+	}
+	obs := make(chan observation, 1)
+	go func() {
+		for m := range ch {
+			select {
+			case obs <- observation{until, m.Params.Result.Context.Slot, m.Params.Result.Value.Err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var startSlot uint64
+	if lastValidBlockHeight == 0 {
+		if s, err := w.HTTP.GetSlot(ctx, StatusProcessed); err == nil {
+			startSlot = s
+		}
+	}
+
+	reached := 0
+	advance := func(o observation) error {
+		r := min(rank(o.commitment), target)
+		for ; reached < r; reached++ {
+			u := Update{Signature: sig, Status: levels[reached], Slot: o.slot, Err: errJSON(o.err), At: time.Now().UTC()}
+			if err := send(u); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	t := time.NewTicker(w.PollInterval)
+	defer t.Stop()
+	for reached < target {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case o := <-obs:
+			if err := advance(o); err != nil {
+				return err
+			}
+		case <-t.C:
+			o, found, err := w.poll(ctx, sig)
+			if err != nil {
+				log.Printf("[sigwatch] %s: %v", sig, err)
+				continue
+			}
+			if found {
+				if err := advance(o); err != nil {
+					return err
+				}
+				continue
+			}
+			// Not known (any more). Until it is confirmed it may never land,
+			// or may have been on a fork that was dropped.
+			if reached >= rank(StatusConfirmed) {
+				continue
+			}
+			expired, slot := w.expired(ctx, lastValidBlockHeight, &startSlot)
+			if expired {
+				return send(Update{Signature: sig, Status: StatusExpired, Slot: slot, At: time.Now().UTC()})
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) poll(ctx context.Context, sig string) (observation, bool, error) {
+	sts, err := w.HTTP.GetSignatureStatuses(ctx, []string{sig}, true)
+	if err != nil || len(sts) == 0 || sts[0] == nil {
+		return observation{}, false, err
+	}
+	st := sts[0]
+	commitment := st.ConfirmationStatus
+	if commitment == "" {
+		commitment = StatusProcessed
+	}
+	return observation{commitment, st.Slot, st.Err}, true, nil
+}
+
+// expired reports whether the transaction can no longer land, along with the
+// slot it was judged at.
+func (w *Watcher) expired(ctx context.Context, lastValidBlockHeight uint64, startSlot *uint64) (bool, uint64) {
+	if lastValidBlockHeight > 0 {
+		h, err := w.HTTP.GetBlockHeight(ctx, StatusConfirmed)
+		if err != nil || h <= lastValidBlockHeight {
+			return false, 0
+		}
+		s, _ := w.HTTP.GetSlot(ctx, StatusConfirmed)
+		return true, s
+	}
+	s, err := w.HTTP.GetSlot(ctx, StatusProcessed)
+	if err != nil {
+		return false, 0
+	}
+	if *startSlot == 0 {
+		*startSlot = s
+	}
+	return s > *startSlot+w.ExpirySlots, s
+}
+
+func errJSON(v any) string {
+	if v == nil {
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
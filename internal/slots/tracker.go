@@ -0,0 +1,144 @@
+package slots
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rileyafox/solana-sentinel/internal/metrics"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+	"github.com/rileyafox/solana-sentinel/internal/store"
+)
+
+// Status is a point-in-time view of the chain head and of how far behind it
+// ingestion is. Zero means "not seen yet".
+type Status struct {
+	Processed uint64    `json:"processed_slot"`
+	Confirmed uint64    `json:"confirmed_slot"`
+	Finalized uint64    `json:"finalized_slot"`
+	Persisted uint64    `json:"persisted_slot"`
+	IngestLag int64     `json:"ingest_lag_slots"` // Processed - Persisted; -1 when unknown
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Tracker follows the chain head: processed slots from slotSubscribe,
+// finalized slots from rootSubscribe, and confirmed slots by polling getSlot
+// (there is no pubsub stream for them). It also polls the newest slot in
+// tx_events to compute ingestion lag.
+type Tracker struct {
+	WS    *rpc.WSClient
+	HTTP  *rpc.HTTPClient
+	Store *store.Store // optional; lag stays unknown without it
+
+	PollInterval time.Duration // confirmed slot + persisted slot refresh
+
+	mu sync.RWMutex
+	st Status
+}
+
+func New(ws *rpc.WSClient, http *rpc.HTTPClient, st *store.Store) *Tracker {
+	return &Tracker{WS: ws, HTTP: http, Store: st, PollInterval: 2 * time.Second}
+}
+
+// Run tracks slots until ctx is done.
+func (t *Tracker) Run(ctx context.Context) error {
+	slots, err := t.WS.SubscribeSlots(ctx)
+	if err != nil {
+		return err
+	}
+	roots, err := t.WS.SubscribeRoots(ctx)
+	if err != nil {
+		return err
+	}
+
+	tick := time.NewTicker(t.PollInterval)
+	defer tick.Stop()
+	t.poll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok := <-slots:
+			if !ok {
+				return ctx.Err()
+			}
+			t.update(func(s *Status) { s.Processed = max(s.Processed, m.Params.Result.Slot) })
+		case m, ok := <-roots:
+			if !ok {
+				return ctx.Err()
+			}
+			t.update(func(s *Status) { s.Finalized = max(s.Finalized, m.Params.Result) })
+		case <-tick.C:
+			t.poll(ctx)
+		}
+	}
+}
+
+// poll refreshes the confirmed slot and the newest persisted slot.
+func (t *Tracker) poll(ctx context.Context) {
+	pctx, cancel := context.WithTimeout(ctx, t.PollInterval)
+	defer cancel()
+
+	if t.HTTP != nil {
+		if slot, err := t.HTTP.GetSlot(pctx, "confirmed"); err != nil {
+			log.Printf("[slots] getSlot confirmed: %v", err)
+		} else {
+			t.update(func(s *Status) { s.Confirmed = max(s.Confirmed, slot) })
+		}
+	}
+	if t.Store != nil {
+		if slot, err := t.Store.LatestSlot(pctx); err != nil {
+			log.Printf("[slots] latest persisted slot: %v", err)
+		} else if slot > 0 {
+			t.update(func(s *Status) { s.Persisted = uint64(slot) })
+		}
+	}
+}
+
+func (t *Tracker) update(fn func(*Status)) {
+	t.mu.Lock()
+	fn(&t.st)
+	t.st.UpdatedAt = time.Now().UTC()
+	t.st.IngestLag = lag(t.st.Processed, t.st.Persisted)
+	s := t.st
+	t.mu.Unlock()
+
+	metrics.ChainSlot.WithLabelValues("processed").Set(float64(s.Processed))
+	metrics.ChainSlot.WithLabelValues("confirmed").Set(float64(s.Confirmed))
+	metrics.ChainSlot.WithLabelValues("finalized").Set(float64(s.Finalized))
+	metrics.PersistedSlot.Set(float64(s.Persisted))
+	if s.IngestLag >= 0 {
+		metrics.IngestLag.Set(float64(s.IngestLag))
+	}
+	if l := lag(s.Processed, s.Confirmed); l >= 0 {
+		metrics.CommitmentLag.WithLabelValues("confirmed").Set(float64(l))
+	}
+	if l := lag(s.Processed, s.Finalized); l >= 0 {
+		metrics.CommitmentLag.WithLabelValues("finalized").Set(float64(l))
+	}
+}
+
+// Snapshot returns the current status. Safe to call on a nil Tracker.
+func (t *Tracker) Snapshot() Status {
+	if t == nil {
+		return Status{IngestLag: -1}
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	s := t.st
+	s.IngestLag = lag(s.Processed, s.Persisted)
+	return s
+}
+
+// lag is head - behind, or -1 if either side is unknown. A negative
+// difference (the poll saw a newer slot than the last notification) is 0.
+func lag(head, behind uint64) int64 {
+	if head == 0 || behind == 0 {
+		return -1
+	}
+	if behind >= head {
+		return 0
+	}
+	return int64(head - behind)
+}
@@ -0,0 +1,295 @@
+// Package spill is a small on-disk FIFO used to hold events while their
+// destination (Redis) is unreachable. Entries are newline-terminated records
+// appended to numbered segment files; a cursor file remembers how far the
+// queue has been drained, so a restart resumes where it left off.
+package spill
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrFull is returned by Append when the queue has reached MaxBytes.
+var ErrFull = errors.New("spill: queue full")
+
+const cursorFile = "cursor"
+
+type segment struct {
+	seq  int
+	size int64
+}
+
+// Queue is safe for concurrent use by one producer and one drainer.
+type Queue struct {
+	Dir          string
+	MaxBytes     int64 // total size of segment files on disk
+	SegmentBytes int64 // roll to a new segment after this many bytes
+
+	mu      sync.Mutex
+	segs    []segment // oldest first; the last one is being written
+	w       *os.File
+	r       *os.File
+	rr      *bufio.Reader
+	head    []byte // entry returned by peek, not yet advanced past
+	readOff int64  // offset into segs[0]
+	dirty   int    // entries drained since the cursor was last saved
+}
+
+// Open loads (or creates) a queue in dir.
+func Open(dir string, maxBytes int64) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	q := &Queue{Dir: dir, MaxBytes: maxBytes, SegmentBytes: 8 << 20}
+	names, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		seq, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(name), ".seg"))
+		if err != nil {
+			continue
+		}
+		st, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		q.segs = append(q.segs, segment{seq: seq, size: st.Size()})
+	}
+	sort.Slice(q.segs, func(i, j int) bool { return q.segs[i].seq < q.segs[j].seq })
+	if len(q.segs) > 0 {
+		if err := q.trimTail(&q.segs[len(q.segs)-1]); err != nil {
+			return nil, err
+		}
+	}
+
+	if b, err := os.ReadFile(filepath.Join(dir, cursorFile)); err == nil {
+		var seq int
+		var off int64
+		if _, err := fmt.Sscanf(string(b), "%d %d", &seq, &off); err == nil {
+			// drop segments the cursor has moved past
+			for len(q.segs) > 0 && q.segs[0].seq < seq {
+				_ = os.Remove(q.segPath(q.segs[0].seq))
+				q.segs = q.segs[1:]
+			}
+			if len(q.segs) > 0 && q.segs[0].seq == seq {
+				q.readOff = off
+			}
+		}
+	}
+	return q, nil
+}
+
+// trimTail cuts a record torn by a crash off the end of the newest segment,
+// so the next Append starts on a fresh line.
+func (q *Queue) trimTail(seg *segment) error {
+	b, err := os.ReadFile(q.segPath(seg.seq))
+	if err != nil {
+		return err
+	}
+	keep := int64(bytes.LastIndexByte(b, '\n') + 1)
+	if keep == seg.size {
+		return nil
+	}
+	seg.size = keep
+	return os.Truncate(q.segPath(seg.seq), keep)
+}
+
+func (q *Queue) segPath(seq int) string {
+	return filepath.Join(q.Dir, fmt.Sprintf("%016d.seg", seq))
+}
+
+// Append adds one entry. Entries must not contain newlines.
+func (q *Queue) Append(entry []byte) error {
+	if bytes.IndexByte(entry, '\n') >= 0 {
+		return errors.New("spill: entry contains a newline")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := int64(len(entry) + 1)
+	if q.diskBytesLocked()+n > q.MaxBytes {
+		return ErrFull
+	}
+	if q.w == nil || q.segs[len(q.segs)-1].size+n > q.SegmentBytes {
+		if err := q.rollLocked(); err != nil {
+			return err
+		}
+	}
+	if _, err := q.w.Write(append(entry, '\n')); err != nil {
+		return err
+	}
+	q.segs[len(q.segs)-1].size += n
+	return nil
+}
+
+func (q *Queue) rollLocked() error {
+	if q.w != nil {
+		_ = q.w.Close()
+		q.w = nil
+	}
+	seq := 1
+	if len(q.segs) > 0 {
+		seq = q.segs[len(q.segs)-1].seq + 1
+	}
+	f, err := os.OpenFile(q.segPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	q.w = f
+	q.segs = append(q.segs, segment{seq: seq})
+	return nil
+}
+
+func (q *Queue) diskBytesLocked() int64 {
+	var total int64
+	for _, s := range q.segs {
+		total += s.size
+	}
+	return total
+}
+
+// Pending is the number of bytes appended but not yet drained.
+func (q *Queue) Pending() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.diskBytesLocked() - q.readOff
+}
+
+// Drain calls fn for each entry, oldest first, until the queue is empty or fn
+// fails. The failed entry stays at the head and is retried by the next
+// Drain. It returns how many entries were delivered. After a crash, up to a
+// hundred entries that were already delivered may be delivered again.
+func (q *Queue) Drain(fn func([]byte) error) (int, error) {
+	n := 0
+	defer q.saveCursor()
+	for {
+		entry, err := q.peek()
+		if err != nil || entry == nil {
+			return n, err
+		}
+		if err := fn(entry); err != nil {
+			return n, err
+		}
+		q.advance(int64(len(entry) + 1))
+		n++
+	}
+}
+
+// peek returns the head entry without consuming it, or nil when empty.
+func (q *Queue) peek() ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.head != nil {
+		return q.head, nil
+	}
+	for len(q.segs) > 0 {
+		seg := q.segs[0]
+		if q.readOff < seg.size {
+			if q.r == nil {
+				f, err := os.Open(q.segPath(seg.seq))
+				if err != nil {
+					return nil, err
+				}
+				if _, err := f.Seek(q.readOff, io.SeekStart); err != nil {
+					f.Close()
+					return nil, err
+				}
+				q.r, q.rr = f, bufio.NewReader(f)
+			}
+			line, err := q.rr.ReadBytes('\n')
+			if err == nil {
+				q.head = bytes.TrimSuffix(line, []byte{'\n'})
+				return q.head, nil
+			}
+			q.closeReaderLocked()
+			if err != io.EOF {
+				return nil, err
+			}
+			if len(q.segs) == 1 {
+				return nil, nil
+			}
+			// A torn record at the end of an older segment: skip the rest.
+			q.readOff = seg.size
+		}
+		if len(q.segs) == 1 {
+			return nil, nil // fully drained; keep the active segment
+		}
+		// Head segment done: delete it and move on.
+		q.closeReaderLocked()
+		_ = os.Remove(q.segPath(seg.seq))
+		q.segs, q.readOff = q.segs[1:], 0
+		q.saveCursorLocked()
+	}
+	return nil, nil
+}
+
+func (q *Queue) advance(n int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.head = nil
+	q.readOff += n
+	q.dirty++
+	if q.dirty >= 100 {
+		q.saveCursorLocked()
+	}
+	if len(q.segs) == 1 && q.readOff == q.segs[0].size {
+		// Everything drained: start over with an empty file.
+		q.closeReaderLocked()
+		if q.w != nil {
+			_ = q.w.Close()
+			q.w = nil
+		}
+		_ = os.Remove(q.segPath(q.segs[0].seq))
+		q.segs, q.readOff = nil, 0
+		q.saveCursorLocked()
+	}
+}
+
+func (q *Queue) closeReaderLocked() {
+	if q.r != nil {
+		_ = q.r.Close()
+		q.r, q.rr = nil, nil
+	}
+}
+
+func (q *Queue) saveCursor() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.saveCursorLocked()
+}
+
+func (q *Queue) saveCursorLocked() {
+	seq := 0
+	if len(q.segs) > 0 {
+		seq = q.segs[0].seq
+	}
+	tmp := filepath.Join(q.Dir, cursorFile+".tmp")
+	if err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", seq, q.readOff)), 0o644); err == nil {
+		_ = os.Rename(tmp, filepath.Join(q.Dir, cursorFile))
+	}
+	q.dirty = 0
+}
+
+// Close closes open files and saves the cursor.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closeReaderLocked()
+	q.saveCursorLocked()
+	if q.w != nil {
+		err := q.w.Close()
+		q.w = nil
+		return err
+	}
+	return nil
+}
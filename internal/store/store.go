@@ -39,6 +39,13 @@ func New(ctx context.Context, dsn string) (*Store, error) {
 
 func (s *Store) Close() { if s != nil && s.pool != nil { s.pool.Close() } }
 
+// LatestSlot returns the newest slot persisted in tx_events (0 when empty).
+func (s *Store) LatestSlot(ctx context.Context) (int64, error) {
+	var slot int64
+	err := s.pool.QueryRow(ctx, `SELECT COALESCE(MAX(slot), 0) FROM tx_events`).Scan(&slot)
+	return slot, err
+}
+
 // Health pings the DB.
 func (s *Store) Health(ctx context.Context) error {
 	var one int
@@ -56,6 +63,10 @@ CREATE TABLE IF NOT EXISTS tx_events (
   created_at TIMESTAMPTZ NOT NULL DEFAULT now()
 );
 CREATE INDEX IF NOT EXISTS tx_events_slot_idx ON tx_events(slot DESC);
+ALTER TABLE tx_events ADD COLUMN IF NOT EXISTS commitment TEXT NOT NULL DEFAULT 'confirmed';
+ALTER TABLE tx_events ADD COLUMN IF NOT EXISTS status_updated_at TIMESTAMPTZ NULL;
+CREATE INDEX IF NOT EXISTS tx_events_pending_idx ON tx_events(slot)
+  WHERE commitment IN ('processed', 'confirmed');
 DO $$
 BEGIN
   IF NOT EXISTS (
@@ -73,17 +84,82 @@ func (s *Store) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, e
 }
 
 
+// Commitment levels tracked on tx_events. RolledBack marks a signature that
+// was seen at processed/confirmed but never finalized (its slot was dropped).
+const (
+	CommitmentProcessed  = "processed"
+	CommitmentConfirmed  = "confirmed"
+	CommitmentFinalized  = "finalized"
+	CommitmentRolledBack = "rolled_back"
+)
+
+// commitmentUpsertSQL keeps the strongest commitment on conflict, so a late
+// duplicate at confirmed never downgrades a finalized row. A rolled-back row
+// seen again is live again and takes the new value.
+const commitmentUpsertSQL = `
+      commitment = CASE
+        WHEN tx_events.commitment = 'finalized' THEN tx_events.commitment
+        WHEN tx_events.commitment = 'confirmed' AND EXCLUDED.commitment = 'processed' THEN tx_events.commitment
+        ELSE EXCLUDED.commitment
+      END`
+
 // UpsertTxEvent writes/updates a single event by signature.
-func (s *Store) UpsertTxEvent(ctx context.Context, signature string, slot int64, errJSON string, logs string) error {
-	const q = `
-INSERT INTO tx_events (signature, slot, err, logs)
-VALUES ($1, $2, $3::jsonb, $4)
+func (s *Store) UpsertTxEvent(ctx context.Context, signature string, slot int64, errJSON string, logs string, commitment string) error {
+	if commitment == "" {
+		commitment = CommitmentConfirmed
+	}
+	q := `
+INSERT INTO tx_events (signature, slot, err, logs, commitment)
+VALUES ($1, $2, $3::jsonb, $4, $5)
 ON CONFLICT (signature) DO UPDATE
   SET slot = EXCLUDED.slot,
       err  = EXCLUDED.err,
-      logs = EXCLUDED.logs;
+      logs = EXCLUDED.logs,` + commitmentUpsertSQL + `;
 `
-	_, err := s.pool.Exec(ctx, q, signature, slot, errJSON, logs)
+	_, err := s.pool.Exec(ctx, q, signature, slot, errJSON, logs, commitment)
+	return err
+}
+
+// PendingTx is a tx_events row that has not reached finalized yet.
+type PendingTx struct {
+	Signature  string
+	Slot       int64
+	Commitment string
+	CreatedAt  time.Time
+}
+
+// ListPendingCommitment returns the oldest rows created after since that are
+// still at processed/confirmed.
+func (s *Store) ListPendingCommitment(ctx context.Context, since time.Time, limit int) ([]PendingTx, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT signature, slot, commitment, created_at
+FROM tx_events
+WHERE commitment IN ('processed', 'confirmed')
+  AND created_at > $1
+ORDER BY slot ASC
+LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PendingTx
+	for rows.Next() {
+		var p PendingTx
+		if err := rows.Scan(&p.Signature, &p.Slot, &p.Commitment, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// SetCommitment records a status change found by the reconciler.
+func (s *Store) SetCommitment(ctx context.Context, signature string, slot int64, commitment string) error {
+	_, err := s.pool.Exec(ctx, `
+UPDATE tx_events
+SET commitment = $2, slot = $3, status_updated_at = now()
+WHERE signature = $1`, signature, commitment, slot)
 	return err
 }
 
@@ -155,12 +231,13 @@ LIMIT $` + itoa(len(args))
 
 // TxRow and EventRow are kept so internal/backfill/parse can compile unchanged.
 type TxRow struct {
-	Signature string
-	Slot      int64
-	BlockTime *time.Time
-	Fee       int64
-	ErrJSON   []byte // JSONB
-	RawJSON   []byte // JSONB (we'll stash this into tx_events.logs for now)
+	Signature  string
+	Slot       int64
+	BlockTime  *time.Time
+	Fee        int64
+	ErrJSON    []byte // JSONB
+	RawJSON    []byte // JSONB (we'll stash this into tx_events.logs for now)
+	Commitment string // commitment the tx was fetched at; empty = confirmed
 }
 
 type EventRow struct {
@@ -185,7 +262,7 @@ func (s *Store) InsertTransaction(ctx context.Context, tx TxRow) error {
 	if tx.RawJSON != nil && len(tx.RawJSON) > 0 {
 		logs = string(tx.RawJSON)
 	}
-	return s.UpsertTxEvent(ctx, tx.Signature, tx.Slot, errJSON, logs)
+	return s.UpsertTxEvent(ctx, tx.Signature, tx.Slot, errJSON, logs, tx.Commitment)
 }
 
 // ReplaceEventsForSignature is a no-op placeholder until a structured events table exists.
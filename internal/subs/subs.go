@@ -0,0 +1,299 @@
+package subs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rileyafox/solana-sentinel/internal/rpc"
+)
+
+// Subscription kinds understood by sol-ingester.
+const (
+	KindLogs    = "logs"    // logsSubscribe mentioning Address (or "all")
+	KindAccount = "account" // accountSubscribe on Address
+	KindProgram = "program" // programSubscribe on Address (program id) with Filters
+)
+
+// maxFilters is the node-side limit on programSubscribe filters.
+const maxFilters = 4
+
+// Redis keys shared by the admin API (writer) and sol-ingester (reader).
+const (
+	setKey     = "sol:subs"
+	seededKey  = "sol:subs:seeded"
+	changedKey = "sol:subs:changed"
+)
+
+// Subscription is one entry in the ingester's watch list.
+type Subscription struct {
+	ID         string              `json:"id"`
+	Kind       string              `json:"kind"`
+	Address    string              `json:"address"`              // pubkey; logs also accept "all"
+	Commitment string              `json:"commitment,omitempty"` // empty = ingester default
+	Encoding   string              `json:"encoding,omitempty"`   // account data encoding (account/program kinds)
+	Filters    []rpc.ProgramFilter `json:"filters,omitempty"`    // program kind only
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+var ErrInvalid = errors.New("invalid subscription")
+
+// Normalize fills defaults and validates s.
+func (s Subscription) Normalize() (Subscription, error) {
+	s.Kind = strings.TrimSpace(strings.ToLower(s.Kind))
+	s.Address = strings.TrimSpace(s.Address)
+	s.Commitment = strings.TrimSpace(s.Commitment)
+	s.Encoding = strings.TrimSpace(s.Encoding)
+	if s.Kind == "" {
+		s.Kind = KindLogs
+	}
+	switch s.Kind {
+	case KindLogs:
+		if s.Encoding != "" || len(s.Filters) > 0 {
+			return s, fmt.Errorf("%w: encoding and filters do not apply to logs subscriptions", ErrInvalid)
+		}
+	case KindAccount, KindProgram:
+		if s.Address == "all" {
+			return s, fmt.Errorf("%w: %s subscriptions need a pubkey", ErrInvalid, s.Kind)
+		}
+		switch s.Encoding {
+		case "", "base58", "base64", "base64+zstd", "jsonParsed":
+		default:
+			return s, fmt.Errorf("%w: unknown encoding %q", ErrInvalid, s.Encoding)
+		}
+		if s.Kind == KindAccount && len(s.Filters) > 0 {
+			return s, fmt.Errorf("%w: filters only apply to program subscriptions", ErrInvalid)
+		}
+		if err := validateFilters(s.Filters); err != nil {
+			return s, err
+		}
+	default:
+		return s, fmt.Errorf("%w: unknown kind %q", ErrInvalid, s.Kind)
+	}
+	if s.Address == "" {
+		return s, fmt.Errorf("%w: address required", ErrInvalid)
+	}
+	switch s.Commitment {
+	case "", "processed", "confirmed", "finalized":
+	default:
+		return s, fmt.Errorf("%w: unknown commitment %q", ErrInvalid, s.Commitment)
+	}
+	if s.ID == "" {
+		s.ID = s.Kind + ":" + s.Address
+		if len(s.Filters) > 0 {
+			// several filtered views of one program may coexist
+			sum := sha1.Sum(filtersJSON(s.Filters))
+			s.ID += ":" + hex.EncodeToString(sum[:4])
+		}
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now().UTC()
+	}
+	return s, nil
+}
+
+func validateFilters(fs []rpc.ProgramFilter) error {
+	if len(fs) > maxFilters {
+		return fmt.Errorf("%w: at most %d filters", ErrInvalid, maxFilters)
+	}
+	for i, f := range fs {
+		switch {
+		case f.Memcmp != nil && f.DataSize != nil, f.Memcmp == nil && f.DataSize == nil:
+			return fmt.Errorf("%w: filter %d must set exactly one of memcmp/dataSize", ErrInvalid, i)
+		case f.Memcmp != nil:
+			if f.Memcmp.Bytes == "" {
+				return fmt.Errorf("%w: filter %d: memcmp bytes required", ErrInvalid, i)
+			}
+			switch f.Memcmp.Encoding {
+			case "", "base58", "base64":
+			default:
+				return fmt.Errorf("%w: filter %d: unknown memcmp encoding %q", ErrInvalid, i, f.Memcmp.Encoding)
+			}
+		}
+	}
+	return nil
+}
+
+func filtersJSON(fs []rpc.ProgramFilter) []byte {
+	if len(fs) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(fs)
+	return b
+}
+
+// Equal reports whether two subscriptions would produce the same RPC subscribe call.
+func (s Subscription) Equal(o Subscription) bool {
+	return s.ID == o.ID && s.Kind == o.Kind && s.Address == o.Address &&
+		s.Commitment == o.Commitment && s.Encoding == o.Encoding &&
+		bytes.Equal(filtersJSON(s.Filters), filtersJSON(o.Filters))
+}
+
+// FromEnv builds the seed watch list: logs subscriptions for SUBSCRIBE_PROGRAMS /
+// SUBSCRIBE_ACCOUNTS style lists and account subscriptions for watched accounts.
+// An empty input yields a single "all" logs subscription, matching the old default.
+func FromEnv(programs, accounts, watched []string) []Subscription {
+	var out []Subscription
+	for _, a := range append(append([]string{}, programs...), accounts...) {
+		if s, err := (Subscription{Kind: KindLogs, Address: a}).Normalize(); err == nil {
+			out = append(out, s)
+		}
+	}
+	for _, a := range watched {
+		if s, err := (Subscription{Kind: KindAccount, Address: a, Encoding: "base64"}).Normalize(); err == nil {
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		s, _ := Subscription{Kind: KindLogs, Address: "all"}.Normalize()
+		out = append(out, s)
+	}
+	return out
+}
+
+// Store keeps the watch list in a Redis hash (id -> JSON) and announces
+// changes on a pub/sub channel so ingesters can apply them live.
+type Store struct {
+	rdb *redis.Client
+}
+
+func NewStore(rdb *redis.Client) *Store { return &Store{rdb: rdb} }
+
+// List returns all subscriptions ordered by ID.
+func (s *Store) List(ctx context.Context) ([]Subscription, error) {
+	raw, err := s.rdb.HGetAll(ctx, setKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Subscription, 0, len(raw))
+	for id, v := range raw {
+		var sub Subscription
+		if err := json.Unmarshal([]byte(v), &sub); err != nil {
+			log.Printf("subs: skip malformed entry %s: %v", id, err)
+			continue
+		}
+		out = append(out, sub)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// Put adds or replaces a subscription and notifies watchers.
+func (s *Store) Put(ctx context.Context, sub Subscription) (Subscription, error) {
+	sub, err := sub.Normalize()
+	if err != nil {
+		return sub, err
+	}
+	b, _ := json.Marshal(sub)
+	if err := s.rdb.HSet(ctx, setKey, sub.ID, b).Err(); err != nil {
+		return sub, err
+	}
+	s.notify(ctx)
+	return sub, nil
+}
+
+// Delete removes a subscription by ID. It reports whether anything was removed.
+func (s *Store) Delete(ctx context.Context, id string) (bool, error) {
+	n, err := s.rdb.HDel(ctx, setKey, id).Result()
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		s.notify(ctx)
+	}
+	return n > 0, nil
+}
+
+// Seed writes subs only the first time it is called against a Redis instance,
+// so env-provided defaults never resurrect entries an operator has deleted.
+// The entries and the seeded marker are written in one transaction: a failed
+// seed leaves nothing behind and is tried again on the next start.
+func (s *Store) Seed(ctx context.Context, subs []Subscription) error {
+	entries := make(map[string]any, len(subs))
+	for _, sub := range subs {
+		sub, err := sub.Normalize()
+		if err != nil {
+			return err
+		}
+		b, _ := json.Marshal(sub)
+		entries[sub.ID] = b
+	}
+	err := s.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		n, err := tx.Exists(ctx, seededKey).Result()
+		if err != nil || n > 0 {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if len(entries) > 0 {
+				pipe.HSet(ctx, setKey, entries)
+			}
+			pipe.Set(ctx, seededKey, time.Now().UTC().Format(time.RFC3339), 0)
+			return nil
+		})
+		if err == nil && len(entries) > 0 {
+			s.notify(ctx)
+		}
+		return err
+	}, seededKey)
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil // another replica seeded first
+	}
+	return err
+}
+
+func (s *Store) notify(ctx context.Context) {
+	if err := s.rdb.Publish(ctx, changedKey, "1").Err(); err != nil {
+		log.Printf("subs: publish change: %v", err)
+	}
+}
+
+// Watch calls fn with the full set whenever it changes. It listens on the
+// change channel and also re-reads every interval in case a message was missed.
+// Blocks until ctx is done.
+func (s *Store) Watch(ctx context.Context, every time.Duration, fn func([]Subscription)) {
+	ps := s.rdb.Subscribe(ctx, changedKey)
+	defer ps.Close()
+	changed := ps.Channel()
+
+	t := time.NewTicker(every)
+	defer t.Stop()
+
+	var last []Subscription
+	loaded := false
+	for {
+		cur, err := s.List(ctx)
+		if err != nil {
+			log.Printf("subs: list: %v", err)
+		} else if !loaded || !sameSet(last, cur) {
+			last, loaded = cur, true
+			fn(cur)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-t.C:
+		}
+	}
+}
+
+func sameSet(a, b []Subscription) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"log"
+	"math"
 	"os"
 	"strconv"
 	"time"
@@ -13,6 +14,7 @@ import (
 
 // RunRedisToPostgres consumes Redis stream events from "sol:logs" and
 // writes them into Postgres table `tx_events` with an idempotent upsert.
+// Account updates from "sol:accounts" go to `accounts` and `account_history`.
 func RunRedisToPostgres(ctx context.Context) error {
 	// --- Redis client ---
 	redisURL := getenv("REDIS_URL", "redis://redis:6379/0")
@@ -39,17 +41,23 @@ CREATE TABLE IF NOT EXISTS tx_events(
   err        JSONB NULL,
   logs       TEXT  NULL,
   created_at TIMESTAMPTZ NOT NULL DEFAULT now()
-)`); err != nil {
+);
+ALTER TABLE tx_events ADD COLUMN IF NOT EXISTS commitment TEXT NOT NULL DEFAULT 'confirmed';
+ALTER TABLE tx_events ADD COLUMN IF NOT EXISTS status_updated_at TIMESTAMPTZ NULL`); err != nil {
+		return err
+	}
+	if _, err := pool.Exec(ctx, accountsSchema); err != nil {
 		return err
 	}
 
-	lastID := getenv("REDIS_FROM", "$") // "$" = only new items; "0-0" = backfill
-	log.Printf("[worker] redis->pg starting at ID=%s", lastID)
+	from := getenv("REDIS_FROM", "$") // "$" = only new items; "0-0" = backfill
+	lastIDs := map[string]string{"sol:logs": from, "sol:accounts": from}
+	log.Printf("[worker] redis->pg starting at ID=%s", from)
 
 	// Main read loop
 	for ctx.Err() == nil {
 		records, err := rdb.XRead(ctx, &redis.XReadArgs{
-			Streams: []string{"sol:logs", lastID},
+			Streams: []string{"sol:logs", "sol:accounts", lastIDs["sol:logs"], lastIDs["sol:accounts"]},
 			Count:   200,
 			Block:   2 * time.Second, // wait for new items
 		}).Result()
@@ -63,12 +71,26 @@ CREATE TABLE IF NOT EXISTS tx_events(
 			continue
 		}
 
+		// A failed write stops its stream at that entry: lastID stays before
+		// it, so the next XREAD delivers it (and what follows) again.
+		failed := false
 		for _, stream := range records {
+			if stream.Stream == "sol:accounts" {
+				for _, msg := range stream.Messages {
+					if err := upsertAccount(ctx, pool, msg.Values); err != nil {
+						log.Printf("[worker] account upsert error (id=%s): %v", msg.ID, err)
+						failed = true
+						break
+					}
+					lastIDs[stream.Stream] = msg.ID
+				}
+				continue
+			}
 			for _, msg := range stream.Messages {
 				sig := sval(msg.Values["signature"])
 				if sig == "" {
 					// Skip malformed entries
-					lastID = msg.ID
+					lastIDs[stream.Stream] = msg.ID
 					continue
 				}
 
@@ -81,30 +103,106 @@ CREATE TABLE IF NOT EXISTS tx_events(
 				}
 				errJSON := sval(msg.Values["err"])  // "null" or JSON string
 				logs := sval(msg.Values["logs"])    // joined lines
+				commitment := sval(msg.Values["commitment"])
+				if commitment == "" {
+					commitment = "confirmed" // producers before commitment tracking
+				}
 
-				// Idempotent upsert by signature
+				// Idempotent upsert by signature; never downgrade a finalized row
 				_, uerr := pool.Exec(ctx, `
-INSERT INTO tx_events (signature, slot, err, logs)
-VALUES ($1, $2, $3::jsonb, $4)
+INSERT INTO tx_events (signature, slot, err, logs, commitment)
+VALUES ($1, $2, $3::jsonb, $4, $5)
 ON CONFLICT (signature) DO UPDATE
   SET slot = EXCLUDED.slot,
       err  = EXCLUDED.err,
-      logs = EXCLUDED.logs
-`, sig, slotInt, errJSON, logs)
+      logs = EXCLUDED.logs,
+      commitment = CASE
+        WHEN tx_events.commitment = 'finalized' THEN tx_events.commitment
+        WHEN tx_events.commitment = 'confirmed' AND EXCLUDED.commitment = 'processed' THEN tx_events.commitment
+        ELSE EXCLUDED.commitment
+      END
+`, sig, slotInt, errJSON, logs, commitment)
 				if uerr != nil {
 					log.Printf("[worker] upsert error (sig=%s): %v", sig, uerr)
 					// do not advance lastID on failure; try again next read
-					continue
+					failed = true
+					break
 				}
 
-				lastID = msg.ID
+				lastIDs[stream.Stream] = msg.ID
 			}
 		}
+		if failed {
+			time.Sleep(1 * time.Second) // let Postgres recover before re-reading
+		}
 	}
 
 	return ctx.Err()
 }
 
+const accountsSchema = `
+CREATE TABLE IF NOT EXISTS accounts (
+  pubkey TEXT PRIMARY KEY,
+  owner  TEXT NOT NULL,
+  slot   BIGINT NOT NULL,
+  lamports BIGINT NOT NULL,
+  executable BOOLEAN NOT NULL,
+  rent_epoch BIGINT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE TABLE IF NOT EXISTS account_history (
+  pubkey     TEXT NOT NULL,
+  slot       BIGINT NOT NULL,
+  owner      TEXT NOT NULL,
+  lamports   BIGINT NOT NULL,
+  executable BOOLEAN NOT NULL,
+  rent_epoch BIGINT NOT NULL,
+  space      BIGINT NULL,
+  data       JSONB NULL,
+  observed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (pubkey, slot)
+)`
+
+// upsertAccount writes one account_update: a history row keyed by
+// (pubkey, slot) and the latest state, which never moves back in slot.
+func upsertAccount(ctx context.Context, pool *pgxpool.Pool, v map[string]any) error {
+	pubkey := sval(v["pubkey"])
+	if pubkey == "" {
+		return nil // malformed; skip
+	}
+	slot := ival(v["slot"])
+	owner := sval(v["owner"])
+	lamports := ival(v["lamports"])
+	executable := sval(v["executable"]) == "1" || sval(v["executable"]) == "true"
+	rentEpoch := ival(v["rent_epoch"])
+	space := ival(v["space"])
+	var data any // NULL unless the producer included JSON data
+	if d := sval(v["data"]); d != "" {
+		data = d
+	}
+
+	if _, err := pool.Exec(ctx, `
+INSERT INTO account_history (pubkey, slot, owner, lamports, executable, rent_epoch, space, data)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8::jsonb)
+ON CONFLICT (pubkey, slot) DO NOTHING`,
+		pubkey, slot, owner, lamports, executable, rentEpoch, space, data); err != nil {
+		return err
+	}
+	_, err := pool.Exec(ctx, `
+INSERT INTO accounts (pubkey, owner, slot, lamports, executable, rent_epoch, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, now())
+ON CONFLICT (pubkey) DO UPDATE
+  SET owner = EXCLUDED.owner,
+      slot = EXCLUDED.slot,
+      lamports = EXCLUDED.lamports,
+      executable = EXCLUDED.executable,
+      rent_epoch = EXCLUDED.rent_epoch,
+      updated_at = now()
+  WHERE accounts.slot <= EXCLUDED.slot`,
+		pubkey, owner, slot, lamports, executable, rentEpoch)
+	return err
+}
+
 func getenv(k, d string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
@@ -112,6 +210,22 @@ func getenv(k, d string) string {
 	return d
 }
 
+// ival parses a numeric stream field into int64. u64 values above the BIGINT
+// range (e.g. rent_epoch = u64::MAX for rent-exempt accounts) clamp to MaxInt64.
+func ival(v any) int64 {
+	s := sval(v)
+	if s == "" {
+		return 0
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if _, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return math.MaxInt64
+	}
+	return 0
+}
+
 func sval(v any) string {
 	if v == nil {
 		return ""
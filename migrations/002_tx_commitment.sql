@@ -0,0 +1,17 @@
+-- Commitment tracking for ingested transactions.
+-- tx_events is normally bootstrapped by the API/worker; create it here too so
+-- this file also applies on a fresh database.
+CREATE TABLE IF NOT EXISTS tx_events (
+  signature  TEXT PRIMARY KEY,
+  slot       BIGINT NOT NULL,
+  err        JSONB NULL,
+  logs       TEXT  NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- processed | confirmed | finalized | rolled_back
+ALTER TABLE tx_events ADD COLUMN IF NOT EXISTS commitment TEXT NOT NULL DEFAULT 'confirmed';
+ALTER TABLE tx_events ADD COLUMN IF NOT EXISTS status_updated_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS tx_events_pending_idx ON tx_events(slot)
+  WHERE commitment IN ('processed', 'confirmed');
@@ -0,0 +1,15 @@
+-- Per-slot history of watched accounts (accountSubscribe / programSubscribe).
+-- accounts (001_init.sql) keeps the latest state; this keeps every change.
+CREATE TABLE IF NOT EXISTS account_history (
+  pubkey     TEXT NOT NULL,
+  slot       BIGINT NOT NULL,
+  owner      TEXT NOT NULL,
+  lamports   BIGINT NOT NULL,
+  executable BOOLEAN NOT NULL,
+  rent_epoch BIGINT NOT NULL,
+  space      BIGINT NULL,
+  data       JSONB NULL,
+  observed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (pubkey, slot)
+);
+CREATE INDEX IF NOT EXISTS idx_account_history_slot ON account_history(slot DESC);
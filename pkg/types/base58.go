@@ -0,0 +1,76 @@
+package types
+
+import "fmt"
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Index = func() (idx [256]int8) {
+	for i := range idx {
+		idx[i] = -1
+	}
+	for i, c := range base58Alphabet {
+		idx[c] = int8(i)
+	}
+	return idx
+}()
+
+// Base58Encode encodes b with the Bitcoin alphabet Solana uses for
+// addresses, signatures and instruction data.
+func Base58Encode(b []byte) string {
+	zeros := 0
+	for zeros < len(b) && b[zeros] == 0 {
+		zeros++
+	}
+	// log(256)/log(58) < 1.37
+	digits := make([]byte, 0, len(b)*137/100+1)
+	for _, c := range b[zeros:] {
+		carry := int(c)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % 58)
+			carry /= 58
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry%58))
+			carry /= 58
+		}
+	}
+	out := make([]byte, zeros+len(digits))
+	for i := 0; i < zeros; i++ {
+		out[i] = '1'
+	}
+	for i, d := range digits {
+		out[len(out)-1-i] = base58Alphabet[d]
+	}
+	return string(out)
+}
+
+// Base58Decode is the inverse of Base58Encode.
+func Base58Decode(s string) ([]byte, error) {
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+	bytes := make([]byte, 0, len(s)*733/1000+1) // log(58)/log(256) < 0.733
+	for i := zeros; i < len(s); i++ {
+		v := base58Index[s[i]]
+		if v < 0 {
+			return nil, fmt.Errorf("base58: invalid character %q", s[i])
+		}
+		carry := int(v)
+		for j := range bytes {
+			carry += int(bytes[j]) * 58
+			bytes[j] = byte(carry)
+			carry >>= 8
+		}
+		for carry > 0 {
+			bytes = append(bytes, byte(carry))
+			carry >>= 8
+		}
+	}
+	out := make([]byte, zeros+len(bytes))
+	for i, b := range bytes {
+		out[len(out)-1-i] = b
+	}
+	return out, nil
+}
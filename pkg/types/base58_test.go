@@ -0,0 +1,77 @@
+package types
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBase58(t *testing.T) {
+	tests := []struct {
+		hex string
+		b58 string
+	}{
+		// Bitcoin Core's base58_encode_decode vectors.
+		{"", ""},
+		{"61", "2g"},
+		{"626262", "a3gV"},
+		{"636363", "aPEr"},
+		{"73696d706c792061206c6f6e6720737472696e67", "2cFupjhnEsSn59qHXstmK2ffpLv2"},
+		{"00eb15231dfceb60925886b67d065299925915aeb172c06647", "1NS17iag9jJgTHD1VXjvLCEnZuQ3rJDE9L"},
+		{"516b6fcd0f", "ABnLTmg"},
+		{"bf4f89001e670274dd", "3SEo3LWLoPntC"},
+		{"572e4794", "3EFU7m"},
+		{"ecac89cad93923c02321", "EJDM8drfXA6uyA"},
+		{"10c8511e", "Rt5zm"},
+		{"00000000000000000000", "1111111111"},
+
+		// Leading zero bytes are leading '1's, one each.
+		{"00", "1"},
+		{"0000", "11"},
+		{"0001", "12"},
+		{"000000ff", "1115Q"},
+		{"0000000000000000000000000000000000000000000000000000000000000000", "11111111111111111111111111111111"}, // System program
+		{"06ddf6e1d765a193d9cbe146ceeb79ac1cb485ed5f5b37913a8cf5857eff00a9", "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"},
+	}
+	for _, tt := range tests {
+		b, err := hex.DecodeString(tt.hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Base58Encode(b); got != tt.b58 {
+			t.Errorf("Base58Encode(%s) = %q, want %q", tt.hex, got, tt.b58)
+		}
+		got, err := Base58Decode(tt.b58)
+		if err != nil {
+			t.Errorf("Base58Decode(%q): %v", tt.b58, err)
+			continue
+		}
+		if !bytes.Equal(got, b) {
+			t.Errorf("Base58Decode(%q) = %x, want %s", tt.b58, got, tt.hex)
+		}
+	}
+}
+
+func TestBase58DecodeInvalid(t *testing.T) {
+	// 0, O, I and l are not in the alphabet.
+	for _, s := range []string{"0", "O", "I", "l", "1110", "abc!", "2g\x00"} {
+		if _, err := Base58Decode(s); err == nil {
+			t.Errorf("Base58Decode(%q): no error", s)
+		}
+	}
+}
+
+func TestBase58RoundTrip(t *testing.T) {
+	for n := 0; n <= 80; n++ {
+		for _, fill := range []byte{0x00, 0x01, 0x7f, 0xff} {
+			b := bytes.Repeat([]byte{fill}, n)
+			if n > 1 {
+				b[n-1] ^= 0x5a
+			}
+			got, err := Base58Decode(Base58Encode(b))
+			if err != nil || !bytes.Equal(got, b) {
+				t.Fatalf("round trip of %x: %x, %v", b, got, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package types
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// TransactionWithMeta is a getTransaction result (or one transaction of a
+// getBlock result). It decodes from the json, jsonParsed, base58 and base64
+// encodings: account keys always carry signer/writable flags, and
+// instruction accounts are always resolved to addresses, including those
+// loaded from lookup tables. base58/base64 payloads are decoded locally
+// (DecodeTransaction) and read like json.
+type TransactionWithMeta struct {
+	Slot        uint64      `json:"slot"`
+	BlockTime   *int64      `json:"blockTime"`
+	Meta        *TxMeta     `json:"meta"`
+	Transaction Transaction `json:"transaction"`
+	Version     TxVersion   `json:"version,omitempty"`
+}
+
+func (t *TransactionWithMeta) UnmarshalJSON(b []byte) error {
+	type plain TransactionWithMeta
+	if err := json.Unmarshal(b, (*plain)(t)); err != nil {
+		return err
+	}
+	if t.Version == "" {
+		t.Version = t.Transaction.version
+	}
+	t.resolve()
+	return nil
+}
+
+// Signature is the transaction's first signature, which identifies it.
+func (t *TransactionWithMeta) Signature() string {
+	if len(t.Transaction.Signatures) == 0 {
+		return ""
+	}
+	return t.Transaction.Signatures[0]
+}
+
+// Mentions reports whether addr is among the transaction's account keys,
+// including addresses loaded from lookup tables. Program ids are account
+// keys too, so this also matches invoked programs.
+func (t *TransactionWithMeta) Mentions(addr string) bool {
+	for _, k := range t.AccountKeys() {
+		if k.Pubkey == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// AccountKeys lists every account the transaction references, in index
+// order: the message's static keys, then (v0) the addresses loaded from
+// lookup tables, writable before readonly.
+func (t *TransactionWithMeta) AccountKeys() []AccountKey {
+	keys := t.Transaction.Message.AccountKeys
+	for _, k := range keys {
+		if k.Source == SourceLookupTable {
+			return keys // jsonParsed already lists them
+		}
+	}
+	if t.Meta == nil || t.Meta.LoadedAddresses == nil {
+		return keys
+	}
+	out := make([]AccountKey, 0, len(keys)+len(t.Meta.LoadedAddresses.Writable)+len(t.Meta.LoadedAddresses.Readonly))
+	out = append(out, keys...)
+	for _, a := range t.Meta.LoadedAddresses.Writable {
+		out = append(out, AccountKey{Pubkey: a, Writable: true, Source: SourceLookupTable})
+	}
+	for _, a := range t.Meta.LoadedAddresses.Readonly {
+		out = append(out, AccountKey{Pubkey: a, Source: SourceLookupTable})
+	}
+	return out
+}
+
+// resolve fills the flags and addresses that only the jsonParsed encoding
+// spells out, so both encodings read the same.
+func (t *TransactionWithMeta) resolve() {
+	msg := &t.Transaction.Message
+	if h := msg.Header; h != nil {
+		n := len(msg.AccountKeys)
+		for i := range msg.AccountKeys {
+			k := &msg.AccountKeys[i]
+			k.Signer = i < h.NumRequiredSignatures
+			if k.Signer {
+				k.Writable = i < h.NumRequiredSignatures-h.NumReadonlySignedAccounts
+			} else {
+				k.Writable = i < n-h.NumReadonlyUnsignedAccounts
+			}
+			if k.Source == "" {
+				k.Source = SourceTransaction
+			}
+		}
+	}
+	keys := t.AccountKeys()
+	resolveAll(msg.Instructions, keys)
+	if t.Meta != nil {
+		for i := range t.Meta.InnerInstructions {
+			resolveAll(t.Meta.InnerInstructions[i].Instructions, keys)
+		}
+	}
+}
+
+func resolveAll(ins []Instruction, keys []AccountKey) {
+	for i := range ins {
+		ins[i].resolve(keys)
+	}
+}
+
+// Transaction is the signed transaction: signatures and message.
+type Transaction struct {
+	Signatures []string `json:"signatures"`
+	Message    Message  `json:"message"`
+
+	// Encoded is the [data, encoding] pair returned for base58/base64.
+	Encoded []string `json:"-"`
+	// raw is the JSON the node sent, kept for MarshalJSON.
+	raw     json.RawMessage
+	version TxVersion // from the wire format, when decoded locally
+}
+
+func (t *Transaction) UnmarshalJSON(b []byte) error {
+	raw := append(json.RawMessage(nil), b...)
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		var enc []string
+		if err := json.Unmarshal(b, &enc); err != nil {
+			return err
+		}
+		if len(enc) != 2 {
+			return fmt.Errorf("encoded transaction: want [data, encoding], got %d items", len(enc))
+		}
+		tx, version, err := DecodeEncoded(enc[0], enc[1])
+		if err != nil {
+			return fmt.Errorf("decode %s transaction: %w", enc[1], err)
+		}
+		*t = tx
+		t.Encoded, t.raw, t.version = enc, raw, version
+		return nil
+	}
+	type plain Transaction
+	if err := json.Unmarshal(b, (*plain)(t)); err != nil {
+		return err
+	}
+	t.raw = raw
+	return nil
+}
+
+// MarshalJSON returns the node's original JSON when there is one, so stored
+// audit copies keep every field.
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	if t.raw != nil {
+		return t.raw, nil
+	}
+	if t.Encoded != nil {
+		return json.Marshal(t.Encoded)
+	}
+	type plain Transaction
+	return json.Marshal(plain(t))
+}
+
+// MessageHeader counts the signer and readonly accounts of a json-encoded or
+// wire-decoded message (jsonParsed puts the flags on each key instead).
+type MessageHeader struct {
+	NumRequiredSignatures       int `json:"numRequiredSignatures"`
+	NumReadonlySignedAccounts   int `json:"numReadonlySignedAccounts"`
+	NumReadonlyUnsignedAccounts int `json:"numReadonlyUnsignedAccounts"`
+}
+
+type Message struct {
+	AccountKeys         []AccountKey         `json:"accountKeys"`
+	Header              *MessageHeader       `json:"header,omitempty"`
+	RecentBlockhash     string               `json:"recentBlockhash"`
+	Instructions        []Instruction        `json:"instructions"`
+	AddressTableLookups []AddressTableLookup `json:"addressTableLookups,omitempty"`
+}
+
+// Where an account key comes from.
+const (
+	SourceTransaction = "transaction"
+	SourceLookupTable = "lookupTable"
+)
+
+// AccountKey is one account of a message. json encodes keys as bare
+// strings; the flags are then derived from the message header.
+type AccountKey struct {
+	Pubkey   string `json:"pubkey"`
+	Signer   bool   `json:"signer"`
+	Writable bool   `json:"writable"`
+	Source   string `json:"source,omitempty"` // transaction|lookupTable
+}
+
+func (k *AccountKey) UnmarshalJSON(b []byte) error {
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '"' {
+		*k = AccountKey{}
+		return json.Unmarshal(b, &k.Pubkey)
+	}
+	type plain AccountKey
+	return json.Unmarshal(b, (*plain)(k))
+}
+
+// AddressTableLookup loads accounts from an address lookup table (v0).
+type AddressTableLookup struct {
+	AccountKey      string `json:"accountKey"`
+	WritableIndexes []int  `json:"writableIndexes"`
+	ReadonlyIndexes []int  `json:"readonlyIndexes"`
+}
+
+// Instruction is a compiled (json), partially decoded or parsed (jsonParsed)
+// instruction. ProgramID and Accounts are always addresses; the index
+// fields are only set for json and wire-decoded transactions.
+type Instruction struct {
+	ProgramID   string          `json:"programId"`
+	Accounts    []string        `json:"accounts,omitempty"`
+	Data        string          `json:"data,omitempty"`    // base58; not set for parsed instructions
+	Program     string          `json:"program,omitempty"` // parser name, e.g. system, spl-token
+	Parsed      json.RawMessage `json:"parsed,omitempty"`  // {"type","info"} or, for memos, a string
+	StackHeight *int            `json:"stackHeight,omitempty"`
+
+	ProgramIDIndex *int  `json:"programIdIndex,omitempty"`
+	AccountIndexes []int `json:"-"`
+}
+
+func (in *Instruction) UnmarshalJSON(b []byte) error {
+	var aux struct {
+		ProgramID      string            `json:"programId"`
+		ProgramIDIndex *int              `json:"programIdIndex"`
+		Accounts       []json.RawMessage `json:"accounts"`
+		Data           string            `json:"data"`
+		Program        string            `json:"program"`
+		Parsed         json.RawMessage   `json:"parsed"`
+		StackHeight    *int              `json:"stackHeight"`
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	*in = Instruction{
+		ProgramID:      aux.ProgramID,
+		ProgramIDIndex: aux.ProgramIDIndex,
+		Data:           aux.Data,
+		Program:        aux.Program,
+		Parsed:         aux.Parsed,
+		StackHeight:    aux.StackHeight,
+	}
+	for _, a := range aux.Accounts {
+		if len(a) > 0 && a[0] == '"' {
+			var s string
+			if err := json.Unmarshal(a, &s); err != nil {
+				return err
+			}
+			in.Accounts = append(in.Accounts, s)
+			continue
+		}
+		n, err := strconv.Atoi(string(a))
+		if err != nil {
+			return fmt.Errorf("instruction account %s: %w", a, err)
+		}
+		in.AccountIndexes = append(in.AccountIndexes, n)
+	}
+	return nil
+}
+
+// resolve turns json account indexes into addresses.
+func (in *Instruction) resolve(keys []AccountKey) {
+	at := func(i int) string {
+		if i >= 0 && i < len(keys) {
+			return keys[i].Pubkey
+		}
+		return ""
+	}
+	if in.ProgramID == "" && in.ProgramIDIndex != nil {
+		in.ProgramID = at(*in.ProgramIDIndex)
+	}
+	if in.Accounts == nil && in.AccountIndexes != nil {
+		in.Accounts = make([]string, len(in.AccountIndexes))
+		for j, i := range in.AccountIndexes {
+			in.Accounts[j] = at(i)
+		}
+	}
+}
+
+// ParsedInfo returns the type and info of a parsed instruction; ok is false
+// when the node did not parse it (or parsed it to a plain string, as for memos).
+func (in *Instruction) ParsedInfo() (typ string, info json.RawMessage, ok bool) {
+	var p struct {
+		Type string          `json:"type"`
+		Info json.RawMessage `json:"info"`
+	}
+	if len(in.Parsed) == 0 || in.Parsed[0] != '{' || json.Unmarshal(in.Parsed, &p) != nil {
+		return "", nil, false
+	}
+	return p.Type, p.Info, true
+}
+
+// TxMeta is the execution status of a transaction.
+type TxMeta struct {
+	Err                  any                 `json:"err"`
+	Fee                  uint64              `json:"fee"`
+	PreBalances          []uint64            `json:"preBalances"`
+	PostBalances         []uint64            `json:"postBalances"`
+	PreTokenBalances     []TokenBalance      `json:"preTokenBalances"`
+	PostTokenBalances    []TokenBalance      `json:"postTokenBalances"`
+	InnerInstructions    []InnerInstructions `json:"innerInstructions"`
+	LogMessages          []string            `json:"logMessages"`
+	LoadedAddresses      *LoadedAddresses    `json:"loadedAddresses,omitempty"`
+	ComputeUnitsConsumed *uint64             `json:"computeUnitsConsumed,omitempty"`
+	ReturnData           *ReturnData         `json:"returnData,omitempty"`
+
+	raw json.RawMessage
+}
+
+func (m *TxMeta) UnmarshalJSON(b []byte) error {
+	type plain TxMeta
+	if err := json.Unmarshal(b, (*plain)(m)); err != nil {
+		return err
+	}
+	m.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// MarshalJSON returns the node's original JSON when there is one.
+func (m TxMeta) MarshalJSON() ([]byte, error) {
+	if m.raw != nil {
+		return m.raw, nil
+	}
+	type plain TxMeta
+	return json.Marshal(plain(m))
+}
+
+// Failed reports whether the transaction failed on chain.
+func (m *TxMeta) Failed() bool { return m != nil && m.Err != nil }
+
+// TokenBalance is an SPL token balance before or after the transaction.
+type TokenBalance struct {
+	AccountIndex  int         `json:"accountIndex"`
+	Mint          string      `json:"mint"`
+	Owner         string      `json:"owner,omitempty"`
+	ProgramID     string      `json:"programId,omitempty"`
+	UITokenAmount TokenAmount `json:"uiTokenAmount"`
+}
+
+// TokenAmount is a token balance. Amount is the raw integer as a string;
+// UIAmountString applies Decimals.
+type TokenAmount struct {
+	Amount         string   `json:"amount"`
+	Decimals       uint8    `json:"decimals"`
+	UIAmount       *float64 `json:"uiAmount"`
+	UIAmountString string   `json:"uiAmountString"`
+}
+
+// InnerInstructions are the CPIs made by top-level instruction Index.
+type InnerInstructions struct {
+	Index        int           `json:"index"`
+	Instructions []Instruction `json:"instructions"`
+}
+
+// LoadedAddresses are the accounts a v0 transaction loaded from lookup tables.
+type LoadedAddresses struct {
+	Writable []string `json:"writable"`
+	Readonly []string `json:"readonly"`
+}
+
+// ReturnData is what the last program to call set_return_data returned.
+type ReturnData struct {
+	ProgramID string   `json:"programId"`
+	Data      []string `json:"data"` // [base64 data, "base64"]
+}
+
+// Bytes decodes the returned data.
+func (r *ReturnData) Bytes() ([]byte, error) {
+	if len(r.Data) == 0 {
+		return nil, nil
+	}
+	return base64.StdEncoding.DecodeString(r.Data[0])
+}
+
+// TxVersion is "legacy" or a version number ("0"); empty when the node did
+// not say (the request set no maxSupportedTransactionVersion).
+type TxVersion string
+
+func (v *TxVersion) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	switch {
+	case bytes.Equal(b, []byte("null")):
+		*v = ""
+	case len(b) > 0 && b[0] == '"':
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*v = TxVersion(s)
+	default:
+		*v = TxVersion(b)
+	}
+	return nil
+}
+
+func (v TxVersion) MarshalJSON() ([]byte, error) {
+	if _, err := strconv.Atoi(string(v)); err == nil {
+		return []byte(v), nil
+	}
+	return json.Marshal(string(v))
+}
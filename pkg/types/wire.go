@@ -0,0 +1,227 @@
+package types
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var errShort = errors.New("unexpected end of data")
+
+// DecodeTransaction decodes a transaction in Solana's wire format (what
+// base58/base64 encodings carry): signatures, then a legacy or v0 message.
+// The result reads like a json-encoded transaction: keys get their flags
+// and instructions their addresses once it is part of a TransactionWithMeta.
+func DecodeTransaction(b []byte) (Transaction, TxVersion, error) {
+	r := &wireReader{b: b}
+	var tx Transaction
+	n, err := r.compactU16()
+	if err != nil {
+		return tx, "", fmt.Errorf("signatures: %w", err)
+	}
+	tx.Signatures = make([]string, n)
+	for i := range tx.Signatures {
+		sig, err := r.take(64)
+		if err != nil {
+			return tx, "", fmt.Errorf("signature %d: %w", i, err)
+		}
+		tx.Signatures[i] = Base58Encode(sig)
+	}
+	msg, version, err := decodeMessage(r)
+	if err != nil {
+		return tx, "", err
+	}
+	if r.off != len(r.b) {
+		return tx, "", fmt.Errorf("%d trailing bytes", len(r.b)-r.off)
+	}
+	tx.Message = msg
+	return tx, version, nil
+}
+
+// DecodeEncoded decodes a [data, encoding] pair as returned for the base58
+// and base64 encodings.
+func DecodeEncoded(data, encoding string) (Transaction, TxVersion, error) {
+	var b []byte
+	var err error
+	switch encoding {
+	case "base64":
+		b, err = base64.StdEncoding.DecodeString(data)
+	case "base58":
+		b, err = Base58Decode(data)
+	default:
+		return Transaction{}, "", fmt.Errorf("unsupported transaction encoding %q", encoding)
+	}
+	if err != nil {
+		return Transaction{}, "", err
+	}
+	return DecodeTransaction(b)
+}
+
+func decodeMessage(r *wireReader) (Message, TxVersion, error) {
+	var msg Message
+	version := TxVersion("legacy")
+	first, err := r.byte()
+	if err != nil {
+		return msg, "", fmt.Errorf("message: %w", err)
+	}
+	// A versioned message starts with 0x80|version; a legacy one starts with
+	// the header, whose first count is below 128.
+	if first&0x80 != 0 {
+		v := first & 0x7f
+		if v != 0 {
+			return msg, "", fmt.Errorf("unsupported message version %d", v)
+		}
+		version = TxVersion(strconv.Itoa(int(v)))
+		if first, err = r.byte(); err != nil {
+			return msg, "", fmt.Errorf("header: %w", err)
+		}
+	}
+	rest, err := r.take(2)
+	if err != nil {
+		return msg, "", fmt.Errorf("header: %w", err)
+	}
+	msg.Header = &MessageHeader{
+		NumRequiredSignatures:       int(first),
+		NumReadonlySignedAccounts:   int(rest[0]),
+		NumReadonlyUnsignedAccounts: int(rest[1]),
+	}
+
+	n, err := r.compactU16()
+	if err != nil {
+		return msg, "", fmt.Errorf("account keys: %w", err)
+	}
+	msg.AccountKeys = make([]AccountKey, n)
+	for i := range msg.AccountKeys {
+		key, err := r.pubkey()
+		if err != nil {
+			return msg, "", fmt.Errorf("account key %d: %w", i, err)
+		}
+		msg.AccountKeys[i] = AccountKey{Pubkey: key}
+	}
+	if msg.RecentBlockhash, err = r.pubkey(); err != nil {
+		return msg, "", fmt.Errorf("recent blockhash: %w", err)
+	}
+
+	if n, err = r.compactU16(); err != nil {
+		return msg, "", fmt.Errorf("instructions: %w", err)
+	}
+	msg.Instructions = make([]Instruction, n)
+	for i := range msg.Instructions {
+		if msg.Instructions[i], err = decodeInstruction(r); err != nil {
+			return msg, "", fmt.Errorf("instruction %d: %w", i, err)
+		}
+	}
+
+	if version == "legacy" {
+		return msg, version, nil
+	}
+	if n, err = r.compactU16(); err != nil {
+		return msg, "", fmt.Errorf("address table lookups: %w", err)
+	}
+	msg.AddressTableLookups = make([]AddressTableLookup, n)
+	for i := range msg.AddressTableLookups {
+		l := &msg.AddressTableLookups[i]
+		if l.AccountKey, err = r.pubkey(); err != nil {
+			return msg, "", fmt.Errorf("lookup %d: %w", i, err)
+		}
+		if l.WritableIndexes, err = r.indexes(); err != nil {
+			return msg, "", fmt.Errorf("lookup %d: %w", i, err)
+		}
+		if l.ReadonlyIndexes, err = r.indexes(); err != nil {
+			return msg, "", fmt.Errorf("lookup %d: %w", i, err)
+		}
+	}
+	return msg, version, nil
+}
+
+func decodeInstruction(r *wireReader) (Instruction, error) {
+	var in Instruction
+	prog, err := r.byte()
+	if err != nil {
+		return in, err
+	}
+	idx := int(prog)
+	in.ProgramIDIndex = &idx
+	if in.AccountIndexes, err = r.indexes(); err != nil {
+		return in, err
+	}
+	n, err := r.compactU16()
+	if err != nil {
+		return in, err
+	}
+	data, err := r.take(n)
+	if err != nil {
+		return in, err
+	}
+	in.Data = Base58Encode(data)
+	return in, nil
+}
+
+// wireReader walks a wire-format buffer.
+type wireReader struct {
+	b   []byte
+	off int
+}
+
+func (r *wireReader) take(n int) ([]byte, error) {
+	if n < 0 || len(r.b)-r.off < n {
+		return nil, errShort
+	}
+	out := r.b[r.off : r.off+n]
+	r.off += n
+	return out, nil
+}
+
+func (r *wireReader) byte() (byte, error) {
+	b, err := r.take(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *wireReader) pubkey() (string, error) {
+	b, err := r.take(32)
+	if err != nil {
+		return "", err
+	}
+	return Base58Encode(b), nil
+}
+
+// compactU16 reads a "shortvec" length: 7 bits per byte, low bits first,
+// high bit set on every byte but the last, at most 3 bytes.
+func (r *wireReader) compactU16() (int, error) {
+	v := 0
+	for i := 0; i < 3; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= int(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			if v > 0xffff {
+				return 0, fmt.Errorf("compact-u16 overflow")
+			}
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("compact-u16 longer than 3 bytes")
+}
+
+// indexes reads a compact array of one-byte account indexes.
+func (r *wireReader) indexes() ([]int, error) {
+	n, err := r.compactU16()
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.take(n)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int, n)
+	for i, x := range b {
+		out[i] = int(x)
+	}
+	return out, nil
+}